@@ -0,0 +1,162 @@
+package gocrud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ContextKeyAuditActor
+// read by the default Audit.GetActor, set it in a middleware after authentication
+const ContextKeyAuditActor = "gocrud:audit:actor"
+
+var NilAuditDatabaseError = errors.New("database of audit is nil")
+
+type AuditOperation string
+
+const (
+	AuditOperationSave    AuditOperation = "save"
+	AuditOperationDelete  AuditOperation = "delete"
+	AuditOperationRestore AuditOperation = "restore"
+)
+
+// AuditChange
+// json values of a field, Before is null for created records, After is null for hard deleted records
+type AuditChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// AuditDiff
+// json field name to its change
+type AuditDiff map[string]AuditChange
+
+type AuditLog struct {
+	ID        ID             `json:"id"        gorm:"primaryKey"`
+	Model     string         `json:"model"     gorm:"index:idx_audit_logs_record"`
+	RecordID  string         `json:"recordId"  gorm:"index:idx_audit_logs_record"`
+	Operation AuditOperation `json:"operation"`
+	Actor     string         `json:"actor"`
+	Diff      AuditDiff      `json:"diff"      gorm:"serializer:json"`
+	CreatedAt time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// Audit
+// records changes of Crud.Audit and SetupM2MConnectorControllerOptions.Audit into table of AuditLog,
+// censored fields are recorded as they are stored in database, so that there is no plaintext in audit logs.
+type Audit struct {
+	GetActor func(context *gin.Context) string
+}
+
+// NewAudit
+// migrates table of AuditLog with database
+func NewAudit(database *gorm.DB, getActor func(context *gin.Context) string) (*Audit, error) {
+	if database == nil {
+		return nil, NilAuditDatabaseError
+	}
+
+	err := database.AutoMigrate(&AuditLog{})
+	if err != nil {
+		return nil, err
+	}
+
+	if getActor == nil {
+		getActor = func(context *gin.Context) string {
+			return context.GetString(ContextKeyAuditActor)
+		}
+	}
+
+	return &Audit{GetActor: getActor}, nil
+}
+
+func auditFieldsOf(record any) (map[string]json.RawMessage, error) {
+	if record == nil {
+		return nil, nil
+	}
+	if value := reflect.ValueOf(record); value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil, nil
+	}
+
+	content, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(content, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// NewAuditDiff
+// before and after should be pointers of the same struct, or nil
+func NewAuditDiff(before, after any) (AuditDiff, error) {
+	beforeFields, err := auditFieldsOf(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := auditFieldsOf(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := AuditDiff{}
+	for key, b := range beforeFields {
+		if a := afterFields[key]; !bytes.Equal(b, a) {
+			diff[key] = AuditChange{Before: b, After: a}
+		}
+	}
+	for key, a := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			diff[key] = AuditChange{After: a}
+		}
+	}
+
+	return diff, nil
+}
+
+// Log
+// db: use the transaction of the operation, so that the log will be rolled back with it.
+// nothing will be logged if there is no difference between before and after.
+func (a *Audit) Log(
+	db *gorm.DB, context *gin.Context,
+	operation AuditOperation, model, recordID string,
+	before, after any,
+) error {
+	diff, err := NewAuditDiff(before, after)
+	if err != nil {
+		return err
+	}
+
+	if len(diff) == 0 {
+		return nil
+	}
+
+	return db.Create(&AuditLog{
+		Model:     model,
+		RecordID:  recordID,
+		Operation: operation,
+		Actor:     a.GetActor(context),
+		Diff:      diff,
+	}).Error
+}
+
+// History
+// logs of a record, the latest first
+func (a *Audit) History(db *gorm.DB, model, recordID string) ([]AuditLog, error) {
+	var logs []AuditLog
+	err := db.Model(&AuditLog{}).
+		Where("model = ? AND record_id = ?", model, recordID).
+		Order("`id` DESC").
+		Find(&logs).Error
+	return logs, err
+}
@@ -0,0 +1,209 @@
+package gocrud
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	censored "github.com/allape/gocensored"
+	"github.com/allape/gogger"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestAudit(t *testing.T) {
+	db, engine, err := basicSetup("TestAudit.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audit, err := NewAudit(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	censor, err := censored.NewDefaultCensor(&censored.Config{
+		Password: []byte("123456789_0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine.Use(func(context *gin.Context) {
+		context.Set(ContextKeyAuditActor, context.GetHeader("X-Actor"))
+	})
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[SecretUser]{
+		Audit: audit,
+		GetCensors: func(_ *gin.Context, _ *gorm.DB) ([]*censored.Censor, error) {
+			return []*censored.Censor{censor}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.audit.NewAddress(0)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[SecretUser](addr+"/user", CrudyBasicOptions[SecretUser]{
+		HttpClient: &http.Client{Transport: actorTransport("alice")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := crudy.Save(&SecretUser{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = crudy.Patch(user.ID, map[string]any{"priority": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = crudy.Delete(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = crudy.Restore(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := new(R[[]AuditLog])
+	err = MakeJSONRequest(http.DefaultClient, nil, mustBeURL(fmt.Sprintf("%s/user/audit/%d", addr, user.ID)), http.MethodGet, nil, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs := res.Data
+	if len(logs) != 4 {
+		t.Fatalf("expected 4 logs, got %d", len(logs))
+	}
+
+	expectedOperations := []AuditOperation{AuditOperationRestore, AuditOperationDelete, AuditOperationSave, AuditOperationSave}
+	for i, log := range logs {
+		if log.Operation != expectedOperations[i] {
+			t.Fatalf("expected %s at %d, got %s", expectedOperations[i], i, log.Operation)
+		} else if log.Actor != "alice" {
+			t.Fatalf("expected actor alice, got %s", log.Actor)
+		} else if log.Model != "SecretUser" || log.RecordID != fmt.Sprint(user.ID) {
+			t.Fatalf("unexpected log %v", log)
+		}
+	}
+
+	created := logs[3].Diff
+	if string(created["name"].Before) != "null" {
+		t.Fatalf("expected null before, got %s", created["name"].Before)
+	} else if strings.Contains(string(created["name"].After), "Alice") {
+		t.Fatalf("expected encrypted name, got %s", created["name"].After)
+	}
+
+	patched := logs[2].Diff
+	if string(patched["priority"].Before) != "0" || string(patched["priority"].After) != "10" {
+		t.Fatalf("unexpected priority change %v", patched["priority"])
+	}
+
+	if string(logs[1].Diff["deletedAt"].Before) != "null" || string(logs[1].Diff["deletedAt"].After) == "null" {
+		t.Fatalf("unexpected deletedAt change %v", logs[1].Diff["deletedAt"])
+	}
+	if string(logs[0].Diff["deletedAt"].After) != "null" {
+		t.Fatalf("unexpected deletedAt change %v", logs[0].Diff["deletedAt"])
+	}
+}
+
+func TestM2MAudit(t *testing.T) {
+	db, engine, err := basicSetup("TestM2MAudit.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audit, err := NewAudit(db, func(_ *gin.Context) string {
+		return "system"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetupM2MConnectorController[UserTag](
+		engine.Group("/user-tag"), db, gogger.New("controller:user-tag"),
+		"UserID", "TagID",
+		&SetupM2MConnectorControllerOptions[UserTag]{
+			Audit: audit,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.audit.NewAddress(1)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	handler, err := NewM2MConnectorHandler[User, Tag, UserTag](addr+"/user-tag", nil, nil, "UserID", "TagID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = handler.Save([]UserTag{{UserID: 1, TagID: 1}, {UserID: 1, TagID: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = handler.SaveAfterDelete("UserID", 1, []UserTag{{UserID: 1, TagID: 2}, {UserID: 1, TagID: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = handler.Delete(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedOperations := map[string][]AuditOperation{
+		"1,1": {AuditOperationDelete, AuditOperationSave},
+		"1,2": {AuditOperationSave},
+		"1,3": {AuditOperationDelete, AuditOperationSave},
+	}
+	for recordID, operations := range expectedOperations {
+		logs, err := audit.History(db, "UserTag", recordID)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(logs) != len(operations) {
+			t.Fatalf("expected %d logs of %s, got %d", len(operations), recordID, len(logs))
+		}
+		for i, log := range logs {
+			if log.Operation != operations[i] {
+				t.Fatalf("expected %s at %d of %s, got %s", operations[i], i, recordID, log.Operation)
+			} else if log.Actor != "system" {
+				t.Fatalf("expected actor system, got %s", log.Actor)
+			}
+		}
+	}
+}
+
+type actorTransport string
+
+func (a actorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Actor", string(a))
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,101 @@
+package gocrud
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache
+// stores reads of Crud and responses of Crudy, implementations should be safe for concurrent use
+type Cache interface {
+	Get(key string) (any, bool)
+	Set(key string, value any)
+}
+
+// CacheInvalidator
+// such as Crud, whose cached reads depend on records written by others
+type CacheInvalidator interface {
+	InvalidateCache()
+}
+
+type lruCacheEntry struct {
+	key       string
+	value     any
+	expiresAt time.Time
+}
+
+// LRUCache
+// an in-memory Cache, which evicts the least recently used entry once Capacity is exceeded,
+// and expires entries after TTL, entries never expire if TTL is 0
+type LRUCache struct {
+	capacity int
+	ttl      time.Duration
+
+	lock    sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+var _ Cache = (*LRUCache)(nil)
+
+func NewLRUCache(capacity int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(key string) (any, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*lruCacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return entry.value, true
+}
+
+func (c *LRUCache) Set(key string, value any) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry := &lruCacheEntry{key: key, value: value, expiresAt: time.Now().Add(c.ttl)}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// Len
+// returns the count of entries, including expired ones those have not been evicted
+func (c *LRUCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRUCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruCacheEntry).key)
+}
@@ -0,0 +1,241 @@
+package gocrud
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	censored "github.com/allape/gocensored"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := NewLRUCache(2, 0)
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected a")
+	}
+
+	// b is the least recently used one
+	cache.Set("c", 3)
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	} else if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", cache.Len())
+	}
+
+	cache.Set("c", 4)
+	if value, ok := cache.Get("c"); !ok || value != 4 {
+		t.Fatalf("expected c to be 4, got %v", value)
+	} else if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", cache.Len())
+	}
+
+	expiring := NewLRUCache(0, 50*time.Millisecond)
+	expiring.Set("a", 1)
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := expiring.Get("a"); ok {
+		t.Fatal("expected a to be expired")
+	} else if expiring.Len() != 0 {
+		t.Fatalf("expected expired entry to be evicted, got %d", expiring.Len())
+	}
+}
+
+func TestCrudCache(t *testing.T) {
+	db, engine, err := basicSetup("TestCrudCache.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&TenantUser{}, &UserTag{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	censor, err := censored.NewDefaultCensor(&censored.Config{
+		Password: []byte("123456789_0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewLRUCache(100, time.Minute)
+
+	users := &Crud[User]{
+		Cache: cache,
+	}
+	err = Setup(engine.Group("/user"), db, nil, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/secret-user"), db, nil, &Crud[SecretUser]{
+		Cache: cache,
+		GetCensors: func(_ *gin.Context, _ *gorm.DB) ([]*censored.Censor, error) {
+			return []*censored.Censor{censor}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/tenant-user"), db, nil, &Crud[TenantUser]{
+		Cache:   cache,
+		Tenancy: newHeaderTenancy(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetupM2MConnectorController[UserTag](
+		engine.Group("/user-tag"), db, nil,
+		"UserID", "TagID",
+		&SetupM2MConnectorControllerOptions[UserTag]{Invalidates: []CacheInvalidator{users}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.cache.NewAddress(0)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := crudy.Save(&User{Name: "cached"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := crudy.One(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := crudy.Count(nil); err != nil {
+		t.Fatal(err)
+	} else if count != 1 {
+		t.Fatalf("expected 1 user, got %d", count)
+	}
+
+	// changes bypassing Crud are not visible until invalidated
+	err = db.Model(&User{}).Where("id = ?", user.ID).Update("name", "direct").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create(&User{Name: "direct"}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cached, err := crudy.One(user.ID); err != nil {
+		t.Fatal(err)
+	} else if cached.Name != "cached" {
+		t.Fatalf("expected cached name, got %s", cached.Name)
+	}
+	if count, err := crudy.Count(nil); err != nil {
+		t.Fatal(err)
+	} else if count != 1 {
+		t.Fatalf("expected cached count 1, got %d", count)
+	}
+
+	_, err = crudy.Patch(user.ID, map[string]any{"age": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fresh, err := crudy.One(user.ID); err != nil {
+		t.Fatal(err)
+	} else if fresh.Name != "direct" || fresh.Age != 1 {
+		t.Fatalf("expected invalidated record, got %v", fresh)
+	}
+	if count, err := crudy.Count(nil); err != nil {
+		t.Fatal(err)
+	} else if count != 2 {
+		t.Fatalf("expected invalidated count 2, got %d", count)
+	}
+
+	// records are cached before decensored
+	secretCrudy, err := NewCrudy[SecretUser](addr + "/secret-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := secretCrudy.Save(&SecretUser{Name: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if cached, err := secretCrudy.One(secret.ID); err != nil {
+			t.Fatal(err)
+		} else if cached.Name != "secret" {
+			t.Fatalf("expected decensored name, got %s", cached.Name)
+		}
+	}
+
+	// reads are scoped by tenant
+	for _, tenant := range []string{"a", "b"} {
+		tenantCrudy, err := NewCrudy[TenantUser](addr+"/tenant-user", CrudyBasicOptions[TenantUser]{
+			HttpClient: &http.Client{Transport: tenantTransport(tenant)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = tenantCrudy.Save(&TenantUser{Name: fmt.Sprintf("user of %s", tenant)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := tenantCrudy.Page(1, 10, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(list) != 1 || list[0].TenantID != tenant {
+			t.Fatalf("expected only the user of %s, got %v", tenant, list)
+		}
+	}
+
+	// M2M writes invalidate only Cruds listed in Invalidates
+	err = db.Model(&User{}).Where("id = ?", user.ID).Update("name", "connected").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Model(&SecretUser{}).Where("id = ?", secret.ID).Update("name", "").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := NewM2MConnectorHandler[User, Tag, UserTag](addr+"/user-tag", nil, nil, "UserID", "TagID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = handler.Save([]UserTag{{UserID: user.ID, TagID: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fresh, err := crudy.One(user.ID); err != nil {
+		t.Fatal(err)
+	} else if fresh.Name != "connected" {
+		t.Fatalf("expected invalidated record, got %v", fresh)
+	}
+	if cached, err := secretCrudy.One(secret.ID); err != nil {
+		t.Fatal(err)
+	} else if cached.Name != "secret" {
+		t.Fatalf("expected cached secret user, got %v", cached)
+	}
+}
@@ -14,6 +14,8 @@ type Coder interface {
 	NotFound() Code
 	MethodNotAllowed() Code
 	Conflict() Code
+	Forbidden() Code
+	PreconditionFailed() Code
 
 	From(code string) Code
 	FromStatus(status int) Code
@@ -47,6 +49,14 @@ func (d *DefaultCoder) Conflict() Code {
 	return d.FromStatus(http.StatusConflict)
 }
 
+func (d *DefaultCoder) Forbidden() Code {
+	return d.FromStatus(http.StatusForbidden)
+}
+
+func (d *DefaultCoder) PreconditionFailed() Code {
+	return d.FromStatus(http.StatusPreconditionFailed)
+}
+
 func (d *DefaultCoder) From(code string) Code {
 	return Code(code)
 }
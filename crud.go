@@ -1,22 +1,34 @@
 package gocrud
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
 	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/allape/gocensored"
 	"github.com/allape/gogger"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
 	NilGroupError    = errors.New("group is nil")
 	NilDatabaseError = errors.New("database is nil")
+
+	// ContextAbortedError
+	// returned internally when a callback has aborted the context with its own response
+	ContextAbortedError = errors.New("context aborted")
+
+	StaleRecordError = errors.New("record has been modified by others")
 )
 
 var (
@@ -24,6 +36,32 @@ var (
 	DefaultPageSize  = uint64(50)
 )
 
+// BatchSaveError
+// describes the failure of the record at Index of a batch save
+type BatchSaveError struct {
+	Index   int         `json:"index"`
+	Message string      `json:"message"`
+	Fields  FieldErrors `json:"fields,omitempty"`
+}
+
+type BatchSaveErrors []BatchSaveError
+
+func (errs BatchSaveErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = fmt.Sprintf("at %d: %s", err.Index, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+type Pagination[T any] struct {
+	List     []T    `json:"list"`
+	Total    int64  `json:"total"`
+	PageNum  uint64 `json:"pageNum"`
+	PageSize uint64 `json:"pageSize"`
+	HasNext  bool   `json:"hasNext"`
+}
+
 type Crud[T any] struct {
 	DisallowNonstandardPageSize bool
 	DefaultPageSize             uint64
@@ -31,6 +69,19 @@ type Crud[T any] struct {
 
 	SearchHandlers SearchHandlers
 
+	// SelectableFields
+	// json field names those can be selected by search key `fields`, such as `fields=name,age`
+	SelectableFields []string
+
+	// FilterableFields
+	// json field names those can be used in FilterNode of search key `filter`
+	FilterableFields []string
+
+	// Preloads
+	// public name to gorm association path, such as `"orders": "Orders.Items"`,
+	// those can be preloaded by search key `expand`, such as `expand=orders`
+	Preloads map[string]string
+
 	EnableGetAll  bool
 	DisableGetOne bool
 	DisableCount  bool
@@ -38,6 +89,102 @@ type Crud[T any] struct {
 	DisableSave   bool
 	DisableDelete bool
 
+	DisableBatchSave bool
+	DisablePatch     bool
+	DisableRestore   bool
+
+	// EnableCursor
+	// `/cursor/:pageSize` responds CursorPage, which is ordered by applied SortBy and the primary key
+	EnableCursor bool
+
+	// EnablePagination
+	// `/pagination/:pageNum/:pageSize` responds Pagination with the total count
+	EnablePagination bool
+
+	// EnableExport
+	// `/export/:format` streams all searched records as ExportFormat, such as `/export/csv`
+	EnableExport bool
+
+	// EnableImport
+	// `/import/:format` saves every line of body as ExportFormat, and responds ImportReport,
+	// nothing will be committed with `?dryRun=true`
+	EnableImport bool
+
+	// EnableETag
+	// `one`, `page`, `pagination` and `all` respond a weak ETag, and 304 if it matches If-None-Match,
+	// `save`, `patch` and `delete` respond Coder.PreconditionFailed() if records do not match If-Match
+	EnableETag bool
+
+	// EnableEvents
+	// `/events` streams server-sent events of saved, deleted and restored records,
+	// search values of the subscription filter events, such as `/events?name=bob`,
+	// subscribers are matched with one query per event, see CloseEvents for stopping it
+	EnableEvents bool
+	// EventsWithRecord
+	// Event.Record carries the decensored record of `created` and `updated` events
+	EventsWithRecord bool
+
+	// OptimisticLock
+	// reject saves and patches carrying a stale `updatedAt` of Base with Coder.Conflict(),
+	// or a stale VersionField if it is specified
+	OptimisticLock bool
+	// VersionField
+	// object field name of an integer version column, which will be increased on every save
+	VersionField string
+
+	// Transactional
+	// hooks, censors and OnDelete of a save, delete or restore share one transaction,
+	// which will be rolled back if any of them aborts the context, or adds an error with context.Error
+	Transactional bool
+
+	// QueryTimeout
+	// cancels database calls of a request once it is exceeded, database calls are always canceled
+	// once the client disconnects, no timeout if it is 0
+	QueryTimeout time.Duration
+
+	// Audit
+	// logs saves, deletes and restores, and serves history of a record with `/audit/:id`
+	Audit *Audit
+
+	// Webhook
+	// enqueues deliveries of saves, deletes and restores in their transactions, see Webhook.Start
+	Webhook *Webhook
+	// GetWebhookCensors
+	// decensors records of deliveries when they are sent, out of any request,
+	// records are sent as they are stored if it is nil
+	GetWebhookCensors func(ctx context.Context) ([]*censored.Censor, error)
+
+	// Policy
+	// authorizes every operation, and filters rows of reading, writing and deleting with Policy.Scope
+	Policy Policy[T]
+
+	// Tenancy
+	// filters every read by the tenant of the request, stamps it on every write,
+	// and denies accessing records of other tenants by id
+	Tenancy *Tenancy
+
+	// OpenAPI
+	// documents all routes of this Crud
+	OpenAPI *OpenAPI
+
+	// Validator
+	// validates every saved record with struct tags of ValidationTags, DefaultValidator is used if it is nil,
+	// failures are responded with Coder.BadRequest() and FieldErrors as data
+	Validator *Validator
+	// Validators
+	// custom validations called after struct tags are valid
+	Validators []func(record *T, context *gin.Context) FieldErrors
+
+	// Cache
+	// caches `one`, `page`, `pagination`, `all` and `count` by search values and the tenant of the request,
+	// entries are invalidated by saves, deletes and restores of this Crud, and by writes of M2M connectors
+	// those list this Crud in SetupM2MConnectorControllerOptions.Invalidates. Records are cached before decensored,
+	// and reads with Policy or `expand` are not cached.
+	Cache Cache
+	// CacheKey
+	// extra part of cache keys, such as the user of the request if WillGetOne or WillPage filters records by it
+	CacheKey func(context *gin.Context) string
+
 	// Callback func starts with `On` will replace the default operation,
 	//               starts with `Will` will be called before the default operation,
 	// 	             starts with `Did` will be called after the default operation.
@@ -54,6 +201,12 @@ type Crud[T any] struct {
 	WillPage func(pageNum *uint64, pageSize *uint64, context *gin.Context, db *gorm.DB) *gorm.DB
 	DidPage  func(pageNum uint64, pageSize uint64, list []T, context *gin.Context, db *gorm.DB)
 
+	WillCursor func(pageSize *uint64, context *gin.Context, db *gorm.DB) *gorm.DB
+	DidCursor  func(page *CursorPage[T], context *gin.Context, db *gorm.DB)
+
+	WillExport func(context *gin.Context, db *gorm.DB) *gorm.DB
+	DidExport  func(count int64, context *gin.Context, db *gorm.DB)
+
 	WillSave func(record *T, context *gin.Context, db *gorm.DB)
 	DidSave  func(record *T, context *gin.Context, db *gorm.DB)
 
@@ -61,6 +214,10 @@ type Crud[T any] struct {
 	OnDelete   func(context *gin.Context, db *gorm.DB) bool
 	DidDelete  func(context *gin.Context, db *gorm.DB)
 
+	WillRestore func(context *gin.Context, db *gorm.DB)
+	OnRestore   func(context *gin.Context, db *gorm.DB) bool
+	DidRestore  func(context *gin.Context, db *gorm.DB)
+
 	Coder             Coder
 	MakeOkayResponse  func(context *gin.Context, data any)
 	MakeErrorResponse func(context *gin.Context, code Code, err any)
@@ -70,6 +227,19 @@ type Crud[T any] struct {
 	group    *gin.RouterGroup
 	database *gorm.DB
 	logger   *gogger.Logger
+
+	lockField     string
+	lockJSONField string
+	lockColumn    string
+
+	tenantColumn string
+
+	primaryKey *PrimaryKey
+
+	cachePrefix     string
+	cacheGeneration atomic.Uint64
+
+	events *eventHub[T]
 }
 
 // region censors
@@ -78,8 +248,25 @@ func (crud *Crud[T]) encensor(context *gin.Context, db *gorm.DB, record *T) erro
 	return crud.docensor(context, db, record, true)
 }
 
+// decensor
+// preloaded associations will be decensored as well
 func (crud *Crud[T]) decensor(context *gin.Context, db *gorm.DB, record *T) error {
-	return crud.docensor(context, db, record, false)
+	err := crud.docensor(context, db, record, false)
+	if err != nil {
+		return err
+	}
+
+	paths := GetExpandedPaths(context)
+	if len(paths) == 0 {
+		return nil
+	}
+
+	censors, err := crud.GetCensors(context, db)
+	if err != nil {
+		return err
+	}
+
+	return DecensorAssociations(censors, record, paths)
 }
 
 func (crud *Crud[T]) docensor(context *gin.Context, db *gorm.DB, record *T, encensor bool) error {
@@ -87,6 +274,15 @@ func (crud *Crud[T]) docensor(context *gin.Context, db *gorm.DB, record *T, ence
 	if err != nil {
 		return err
 	}
+	if selectedFields, ok := GetSelectedFields(context); ok {
+		for i := range censors {
+			err = CensorFields(censors[i], record, selectedFields, encensor)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	if encensor {
 		for i := range censors {
 			err = censors[i].Encencor(record)
@@ -116,10 +312,155 @@ func (crud *Crud[T]) decensorList(context *gin.Context, db *gorm.DB, list []T) e
 	return nil
 }
 
+// CensorFields
+// only censors the given object fields of record, fields those were not loaded from database will be left untouched
+func CensorFields(censor *censored.Censor, record any, objectFieldNames []string, encensor bool) error {
+	value := reflect.ValueOf(record).Elem()
+	reflected := value.Type()
+
+	var fields []reflect.StructField
+	for _, name := range objectFieldNames {
+		field, ok := reflected.FieldByName(name)
+		// censors only walk through string fields at the top level
+		if !ok || !field.IsExported() || len(field.Index) != 1 || field.Type.Kind() != reflect.String {
+			continue
+		}
+		fields = append(fields, reflect.StructField{Name: field.Name, Type: field.Type, Tag: field.Tag})
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	partial := reflect.New(reflect.StructOf(fields)).Elem()
+	for i, field := range fields {
+		partial.Field(i).Set(value.FieldByName(field.Name))
+	}
+
+	var err error
+	if encensor {
+		err = censor.Encencor(partial.Addr().Interface())
+	} else {
+		err = censor.Decensor(partial.Addr().Interface())
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, field := range fields {
+		value.FieldByName(field.Name).Set(partial.Field(i))
+	}
+
+	return nil
+}
+
 // endregion
 
 // region helper
 
+// selectFields
+// applies `fields` of search values as the columns to select, primary fields will always be selected
+func (crud *Crud[T]) selectFields(context *gin.Context, db *gorm.DB) (*gorm.DB, error) {
+	searches, err := GetSearchValuesFromContext(context)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := PickFirstValuableString(searches[SearchKeyFields])
+	if !ok {
+		return db, nil
+	}
+
+	jsonFields := RemoveDuplication(StringArrayFromCommaSeparatedString(value))
+	for _, jsonField := range jsonFields {
+		if !slices.Contains(crud.SelectableFields, jsonField) {
+			return nil, fmt.Errorf("field %s is not selectable", jsonField)
+		}
+	}
+
+	objectFieldNames, err := GetObjectFieldNameOf[T](jsonFields...)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range crud.primaryKey.Fields {
+		if !slices.Contains(objectFieldNames, field.Name) {
+			objectFieldNames = append(objectFieldNames, field.Name)
+		}
+	}
+
+	columns, err := GetDatabaseFieldNameOf[T](crud.database, objectFieldNames...)
+	if err != nil {
+		return nil, err
+	}
+
+	SetSelectedFields(context, objectFieldNames)
+
+	return db.Select(columns), nil
+}
+
+func (crud *Crud[T]) setupLock() error {
+	crud.lockField = Ternary(crud.VersionField == "", "UpdatedAt", crud.VersionField)
+
+	field, ok := reflect.TypeFor[T]().FieldByName(crud.lockField)
+	if !ok {
+		return fmt.Errorf("lock field %s not found", crud.lockField)
+	}
+
+	if crud.VersionField == "" {
+		if field.Type != reflect.TypeFor[time.Time]() {
+			return fmt.Errorf("lock field %s should be a time.Time", crud.lockField)
+		}
+	} else {
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return fmt.Errorf("version field %s should be an integer", crud.lockField)
+		}
+	}
+
+	jsonFields, err := GetJSONFieldNameOf[T](crud.lockField)
+	if err != nil {
+		return err
+	}
+	crud.lockJSONField = jsonFields[0]
+
+	columns, err := GetDatabaseFieldNameOf[T](crud.database, crud.lockField)
+	if err != nil {
+		return err
+	}
+	crud.lockColumn = columns[0]
+
+	return nil
+}
+
+// setupFilter
+// registers search key `filter` with columns of FilterableFields
+func (crud *Crud[T]) setupFilter() error {
+	objectFieldNames, err := GetObjectFieldNameOf[T](crud.FilterableFields...)
+	if err != nil {
+		return err
+	}
+
+	dbColumns, err := GetDatabaseFieldNameOf[T](crud.database, objectFieldNames...)
+	if err != nil {
+		return err
+	}
+
+	columns := make(map[string]string, len(dbColumns))
+	for i, field := range crud.FilterableFields {
+		columns[field] = dbColumns[i]
+	}
+
+	crud.SearchHandlers = MergeSearchHandlers(
+		SearchHandlers{},
+		crud.SearchHandlers,
+		SearchHandlers{SearchKeyFilter: NewFilterSearchHandler(columns)},
+	)
+
+	return nil
+}
+
 func (crud *Crud[T]) handleSearches(context *gin.Context, db *gorm.DB) (*gorm.DB, error) {
 	return HandleSearch(context, db, crud.SearchHandlers)
 }
@@ -140,17 +481,121 @@ func (crud *Crud[T]) error(context *gin.Context, code Code, err any) {
 	}
 }
 
+// searchError
+// responds Coder.BadRequest() for a FilterError of the request, otherwise logs err and responds Coder.InternalServerError()
+func (crud *Crud[T]) searchError(context *gin.Context, operation string, err error) {
+	var filterError *FilterError
+	if errors.As(err, &filterError) {
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return
+	}
+
+	crud.logger.Error().Printf("%s: failed to handle searches: %v", operation, err)
+	crud.error(context, crud.Coder.InternalServerError(), "[error] search failed")
+}
+
+// keyParams
+// route params of keys, name for a single primary field, or the columns of a composite key
+func (crud *Crud[T]) keyParams(name string) []string {
+	if crud.primaryKey.Composite() {
+		return crud.primaryKey.Columns()
+	}
+	return []string{name}
+}
+
+// keyRoute
+// route of keys, such as `/:id`, or `/:org_id/:code` of a composite key
+func (crud *Crud[T]) keyRoute(name string) string {
+	var route strings.Builder
+	for _, param := range crud.keyParams(name) {
+		route.WriteString("/:" + param)
+	}
+	return route.String()
+}
+
+func (crud *Crud[T]) keyValuesOf(context *gin.Context, name string) []string {
+	params := crud.keyParams(name)
+	values := make([]string, len(params))
+	for i, param := range params {
+		values[i] = context.Param(param)
+	}
+	return values
+}
+
+// keyOf
+// parses the key in route params of keyRoute, false if it is invalid or zero
+func (crud *Crud[T]) keyOf(context *gin.Context, name string) (Key, bool) {
+	key, err := crud.primaryKey.Parse(crud.keyValuesOf(context, name)...)
+	if err != nil || IsZeroKey(key) {
+		return nil, false
+	}
+	return key, true
+}
+
+// keysOf
+// parses comma separated keys in route params of keyRoute, such as `/1,2,3`, or `/1,1/a,b` of a composite key
+func (crud *Crud[T]) keysOf(context *gin.Context, name string) []Key {
+	return crud.primaryKey.ParseMany(crud.keyValuesOf(context, name)...)
+}
+
+// keyOfRecord
+// returns a CompositeKey if T has multiple primary fields
+func (crud *Crud[T]) keyOfRecord(record *T) Key {
+	return crud.primaryKey.Of(record)
+}
+
+// whereKeys
+// filters db by keys of T, see PrimaryKey.Where
+func (crud *Crud[T]) whereKeys(db *gorm.DB, keys ...Key) *gorm.DB {
+	return crud.primaryKey.Where(db, keys)
+}
+
+// isNew
+// reports whether record will be created by saving, which has a zero key,
+// or a composite key which does not exist yet, soft deleted ones are existing
+func (crud *Crud[T]) isNew(db *gorm.DB, record *T) bool {
+	key := crud.keyOfRecord(record)
+	if IsZeroKey(key) || !crud.primaryKey.Composite() {
+		return IsZeroKey(key)
+	}
+
+	var count int64
+	err := crud.whereKeys(db.Model(new(T)).Unscoped(), key).Count(&count).Error
+	if err != nil {
+		crud.logger.Warn().Printf("save: failed to find existing record: %v", err)
+	}
+	return err == nil && count == 0
+}
+
+// databaseOf
+// returns the database bound to the context of request, see WithRequestContext
+func (crud *Crud[T]) databaseOf(context *gin.Context) *gorm.DB {
+	return WithRequestContext(crud.database, context)
+}
+
+// errorWithData
+// responds err as data as well, unless MakeErrorResponse is customized
+func (crud *Crud[T]) errorWithData(context *gin.Context, code Code, err error) {
+	if crud.MakeErrorResponse != nil {
+		crud.MakeErrorResponse(context, code, err)
+	} else {
+		MakeErrorDataResponse(context, code, err, err)
+	}
+}
+
 // endregion
 
 // region primary functions
 
 func (crud *Crud[T]) all(context *gin.Context) {
-	db := crud.database.Model(new(T))
+	db := crud.readable(context)
+	if db == nil {
+		return
+	}
 
 	db, err := crud.handleSearches(context, db)
 	if err != nil {
-		crud.logger.Error().Printf("all: failed to handle searches: %v", err)
-		crud.error(context, crud.Coder.InternalServerError(), "[error] search failed")
+		crud.searchError(context, "all", err)
 		return
 	}
 
@@ -160,14 +605,32 @@ func (crud *Crud[T]) all(context *gin.Context) {
 		}
 	}
 
-	var list []T
-	err = db.Find(&list).Error
+	db, err = crud.selectFields(context, db)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return
+	}
+
+	db, err = crud.expand(context, db)
 	if err != nil {
-		crud.logger.Error().Printf("all: failed to find records: %v", err)
-		crud.error(context, crud.Coder.InternalServerError(), "[error] database failed")
+		crud.error(context, crud.Coder.BadRequest(), err)
 		return
 	}
 
+	var list []T
+	key := crud.cacheKey(context, cacheNamespaceList, "all")
+	if cached, ok := crud.cacheGet(key); ok {
+		list = slices.Clone(cached.([]T))
+	} else {
+		err = db.Find(&list).Error
+		if err != nil {
+			crud.logger.Error().Printf("all: failed to find records: %v", err)
+			crud.error(context, crud.Coder.InternalServerError(), "[error] database failed")
+			return
+		}
+		crud.cacheSet(key, slices.Clone(list))
+	}
+
 	err = crud.decensorList(context, db, list)
 	if err != nil {
 		crud.logger.Error().Printf("all: failed to decensor records: %v", err)
@@ -176,38 +639,59 @@ func (crud *Crud[T]) all(context *gin.Context) {
 	}
 
 	if crud.DidGetAll != nil {
-		if crud.DidGetAll(list, context, crud.database); context.IsAborted() {
+		if crud.DidGetAll(list, context, crud.databaseOf(context)); context.IsAborted() {
 			return
 		}
 	}
 
-	crud.ok(context, list)
+	crud.okWithHashETag(context, list)
 }
 
 func (crud *Crud[T]) one(context *gin.Context) {
 	var result T
 
-	id := Pick(IDsFromCommaSeparatedString(context.Param("id")), 0, 0)
-	if id == 0 {
+	id, ok := crud.keyOf(context, "id")
+	if !ok {
 		crud.error(context, crud.Coder.BadRequest(), "invalid id")
 		return
 	}
 
+	db := crud.readable(context)
+	if db == nil {
+		return
+	}
+
 	if crud.WillGetOne != nil {
-		if crud.WillGetOne(context, crud.database); context.IsAborted() {
+		if db = crud.WillGetOne(context, db); context.IsAborted() {
 			return
 		}
 	}
 
-	db := crud.database.Model(new(T))
+	db, err := crud.selectFields(context, db)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return
+	}
 
-	err := db.Where("id = ?", id).First(&result).Error
+	db, err = crud.expand(context, db)
 	if err != nil {
-		crud.logger.Error().Printf("one: failed to find record: %v", err)
-		crud.error(context, crud.Coder.NotFound(), "not found")
+		crud.error(context, crud.Coder.BadRequest(), err)
 		return
 	}
 
+	key := crud.cacheKey(context, oneCacheNamespace(id))
+	if cached, ok := crud.cacheGet(key); ok {
+		result = cached.(T)
+	} else {
+		err = crud.whereKeys(db, id).First(&result).Error
+		if err != nil {
+			crud.logger.Error().Printf("one: failed to find record: %v", err)
+			crud.error(context, crud.Coder.NotFound(), "not found")
+			return
+		}
+		crud.cacheSet(key, result)
+	}
+
 	err = crud.decensor(context, db, &result)
 	if err != nil {
 		crud.logger.Error().Printf("one: failed to decensor record: %v", err)
@@ -216,24 +700,27 @@ func (crud *Crud[T]) one(context *gin.Context) {
 	}
 
 	if crud.DidGetOne != nil {
-		if crud.DidGetOne(&result, context, crud.database); context.IsAborted() {
+		if crud.DidGetOne(&result, context, crud.databaseOf(context)); context.IsAborted() {
 			return
 		}
 	}
 
-	crud.ok(context, result)
+	crud.okWithRecordETag(context, &result)
 }
 
-func (crud *Crud[T]) page(context *gin.Context) {
+// paginate
+// total will be counted with the same scoped db if withTotal is true,
+// returns nil if the context has been aborted
+func (crud *Crud[T]) paginate(context *gin.Context, withTotal bool) *Pagination[T] {
 	pageNum, err := strconv.ParseUint(context.Param("pageNum"), 10, 64)
 	if err != nil {
 		crud.error(context, crud.Coder.BadRequest(), "invalid page number")
-		return
+		return nil
 	}
 	pageSize, err := strconv.ParseUint(context.Param("pageSize"), 10, 64)
 	if err != nil {
 		crud.error(context, crud.Coder.BadRequest(), "invalid page size")
-		return
+		return nil
 	}
 
 	if pageNum <= 0 {
@@ -244,51 +731,113 @@ func (crud *Crud[T]) page(context *gin.Context) {
 	}
 
 	var list []T
-	db := crud.database.Model(new(T))
+	db := crud.readable(context)
+	if db == nil {
+		return nil
+	}
 
 	db, err = crud.handleSearches(context, db)
 	if err != nil {
-		crud.logger.Error().Printf("page: failed to handle searches: %v", err)
-		crud.error(context, crud.Coder.InternalServerError(), "[error] search failed")
-		return
+		crud.searchError(context, "page", err)
+		return nil
 	}
 
 	if crud.WillPage != nil {
 		if crud.WillPage(&pageNum, &pageSize, context, db); context.IsAborted() {
-			return
+			return nil
 		}
 	}
 
-	db = db.Offset(int((pageNum - 1) * pageSize)).Limit(int(pageSize))
-	err = db.Find(&list).Error
+	pagination := &Pagination[T]{
+		PageNum:  pageNum,
+		PageSize: pageSize,
+	}
+
+	// every query from a new session starts with the same scoped statement
+	countDB := db.Session(&gorm.Session{})
+	db = countDB
+
+	db, err = crud.selectFields(context, db)
 	if err != nil {
-		crud.logger.Error().Printf("page: failed to find records: %v", err)
-		crud.error(context, crud.Coder.InternalServerError(), "[error] database failed")
-		return
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return nil
+	}
+
+	db, err = crud.expand(context, db)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return nil
+	}
+
+	db = db.Offset(int((pageNum - 1) * pageSize)).Limit(int(pageSize))
+
+	key := crud.cacheKey(context, cacheNamespaceList, "page", withTotal, pageNum, pageSize)
+	if cached, ok := crud.cacheGet(key); ok {
+		stored := cached.(Pagination[T])
+		pagination.Total = stored.Total
+		list = slices.Clone(stored.List)
+	} else {
+		if withTotal {
+			err = countDB.Count(&pagination.Total).Error
+			if err != nil {
+				crud.logger.Error().Printf("page: failed to count records: %v", err)
+				crud.error(context, crud.Coder.InternalServerError(), "[error] count failed")
+				return nil
+			}
+		}
+
+		err = db.Find(&list).Error
+		if err != nil {
+			crud.logger.Error().Printf("page: failed to find records: %v", err)
+			crud.error(context, crud.Coder.InternalServerError(), "[error] database failed")
+			return nil
+		}
+
+		crud.cacheSet(key, Pagination[T]{Total: pagination.Total, List: slices.Clone(list)})
+	}
+
+	if withTotal {
+		pagination.HasNext = pageNum*pageSize < uint64(pagination.Total)
 	}
 
 	err = crud.decensorList(context, db, list)
 	if err != nil {
 		crud.logger.Error().Printf("page: failed to decensor records: %v", err)
 		crud.error(context, crud.Coder.InternalServerError(), "[error] decensor failed")
-		return
+		return nil
 	}
 
 	if crud.DidPage != nil {
 		if crud.DidPage(pageNum, pageSize, list, context, db); context.IsAborted() {
-			return
+			return nil
 		}
 	}
 
-	crud.ok(context, list)
+	pagination.List = list
+
+	return pagination
+}
+
+func (crud *Crud[T]) page(context *gin.Context) {
+	if pagination := crud.paginate(context, false); pagination != nil {
+		crud.okWithHashETag(context, pagination.List)
+	}
+}
+
+func (crud *Crud[T]) pagination(context *gin.Context) {
+	if pagination := crud.paginate(context, true); pagination != nil {
+		crud.okWithHashETag(context, pagination)
+	}
 }
 
 func (crud *Crud[T]) count(context *gin.Context) {
-	db := crud.database.Model(new(T))
+	db := crud.readable(context)
+	if db == nil {
+		return
+	}
 	db, err := crud.handleSearches(context, db)
 	if err != nil {
-		crud.logger.Error().Printf("count: failed to handle searches: %v", err)
-		crud.error(context, crud.Coder.InternalServerError(), "[error] search failed")
+		crud.searchError(context, "count", err)
 		return
 	}
 
@@ -299,11 +848,17 @@ func (crud *Crud[T]) count(context *gin.Context) {
 	}
 
 	var count int64
-	err = db.Count(&count).Error
-	if err != nil {
-		crud.logger.Error().Printf("count: failed to count records: %v", err)
-		crud.error(context, crud.Coder.InternalServerError(), "[error] count failed")
-		return
+	key := crud.cacheKey(context, cacheNamespaceCount)
+	if cached, ok := crud.cacheGet(key); ok {
+		count = cached.(int64)
+	} else {
+		err = db.Count(&count).Error
+		if err != nil {
+			crud.logger.Error().Printf("count: failed to count records: %v", err)
+			crud.error(context, crud.Coder.InternalServerError(), "[error] count failed")
+			return
+		}
+		crud.cacheSet(key, count)
 	}
 
 	if crud.DidCount != nil {
@@ -315,45 +870,404 @@ func (crud *Crud[T]) count(context *gin.Context) {
 	crud.ok(context, count)
 }
 
+// checkLock
+// compares lock field of record with the latest one in database,
+// increases the version of record if VersionField is specified
+func (crud *Crud[T]) checkLock(db *gorm.DB, record *T) (Code, error) {
+	value := reflect.ValueOf(record).Elem()
+
+	id := crud.keyOfRecord(record)
+	if IsZeroKey(id) {
+		return "", nil
+	}
+
+	latest := new(T)
+	err := crud.whereKeys(db.Model(new(T)).Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}), id).
+		First(latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	} else if err != nil {
+		crud.logger.Error().Printf("save: failed to find latest record: %v", err)
+		return crud.Coder.InternalServerError(), errors.New("[error] lock failed")
+	}
+
+	provided := value.FieldByName(crud.lockField)
+	current := reflect.ValueOf(latest).Elem().FieldByName(crud.lockField)
+
+	staleError := WithDetails(StaleRecordError, &ErrorDetails{IDs: []Key{id}})
+
+	if crud.VersionField == "" {
+		providedTime := provided.Interface().(time.Time)
+		if !providedTime.IsZero() && current.Interface().(time.Time).After(providedTime) {
+			return crud.Coder.Conflict(), staleError
+		}
+		return "", nil
+	}
+
+	if provided.CanInt() {
+		if provided.Int() != current.Int() {
+			return crud.Coder.Conflict(), staleError
+		}
+		provided.SetInt(current.Int() + 1)
+	} else {
+		if provided.Uint() != current.Uint() {
+			return crud.Coder.Conflict(), staleError
+		}
+		provided.SetUint(current.Uint() + 1)
+	}
+
+	return "", nil
+}
+
+// abortedErrorOf
+// ContextAbortedError wrapping the error a hook responded to the aborted context, see ResponseErrorOf
+func abortedErrorOf(context *gin.Context) (Code, error) {
+	if responseError, ok := ResponseErrorOf(context); ok {
+		return responseError.Code, fmt.Errorf("%w: %w", ContextAbortedError, responseError)
+	} else if err := context.Errors.Last(); err != nil {
+		return "", fmt.Errorf("%w: %w", ContextAbortedError, err.Err)
+	}
+	return "", ContextAbortedError
+}
+
+// saveRecord
+// columns: only these database columns will be updated, otherwise the whole record will be saved
+func (crud *Crud[T]) saveRecord(context *gin.Context, db *gorm.DB, record *T, columns []string) (Code, error) {
+	created := crud.isNew(db, record)
+
+	if code, err := crud.canWrite(context, db, record); err != nil {
+		return code, err
+	}
+
+	if crud.WillSave != nil {
+		if crud.WillSave(record, context, db); context.IsAborted() {
+			return abortedErrorOf(context)
+		}
+		// the tenant stamped by canWrite must not be changed by the hook
+		if err := crud.checkTenant(context, record); err != nil {
+			return crud.Coder.Forbidden(), err
+		}
+	}
+
+	if code, err := crud.validate(context, record); err != nil {
+		return code, err
+	}
+
+	if crud.OptimisticLock {
+		code, err := crud.checkLock(db, record)
+		if err != nil {
+			return code, err
+		}
+	}
+
+	var before *T
+	if crud.Audit != nil {
+		var err error
+		before, err = crud.auditBeforeSave(db, record)
+		if err != nil {
+			crud.logger.Error().Printf("save: failed to find stored record: %v", err)
+			return crud.Coder.InternalServerError(), errors.New("[error] audit failed")
+		}
+	}
+
+	err := crud.encensor(context, db, record)
+	if err != nil {
+		crud.logger.Error().Printf("save: failed to encensor record: %v", err)
+		return crud.Coder.InternalServerError(), errors.New("[error] encensor failed")
+	}
+
+	var res *gorm.DB
+	if len(columns) > 0 {
+		res = db.Model(record).Select(columns).Updates(record)
+	} else {
+		res = db.Save(record)
+	}
+	if res.Error != nil {
+		crud.logger.Error().Printf("save: failed to save record: %v", res.Error)
+		return crud.Coder.InternalServerError(), errors.New("[error] save failed")
+	}
+
+	if crud.Audit != nil {
+		err = crud.Audit.Log(db, context, AuditOperationSave, crud.auditModel(), crud.auditRecordIDOf(record), before, record)
+		if err != nil {
+			crud.logger.Error().Printf("save: failed to audit record: %v", err)
+			return crud.Coder.InternalServerError(), errors.New("[error] audit failed")
+		}
+	}
+
+	// queued censored, it is decensored when it is sent
+	err = crud.enqueueWebhook(db, Ternary(created, EventTypeCreated, EventTypeUpdated), []Key{crud.keyOfRecord(record)}, record)
+	if err != nil {
+		crud.logger.Error().Printf("save: failed to enqueue webhook: %v", err)
+		return crud.Coder.InternalServerError(), errors.New("[error] webhook failed")
+	}
+
+	err = crud.decensor(context, db, record)
+	if err != nil {
+		crud.logger.Error().Printf("save: failed to decensor record: %v", err)
+		return crud.Coder.InternalServerError(), errors.New("[error] decensor failed")
+	}
+
+	if crud.DidSave != nil {
+		// a new session of the result, so that it can be used for other models in the same transaction
+		did := res.Session(&gorm.Session{NewDB: true})
+		did.RowsAffected = res.RowsAffected
+		if crud.DidSave(record, context, did); context.IsAborted() {
+			return abortedErrorOf(context)
+		}
+	}
+
+	return "", nil
+}
+
+// transactional
+// saves and deletes are done in a transaction if Transactional is enabled,
+// or OptimisticLock, Audit or Webhook is enabled, so that the lock check, the audit log and webhook deliveries are atomic with them
+func (crud *Crud[T]) transactional() bool {
+	return crud.Transactional || crud.OptimisticLock || crud.Audit != nil || crud.Webhook != nil
+}
+
+// inTransaction
+// fn will be rolled back if it returns an error, or any hook aborts the context, or adds an error with context.Error
+func (crud *Crud[T]) inTransaction(context *gin.Context, fn func(tx *gorm.DB) error) error {
+	errorCount := len(context.Errors)
+
+	return crud.databaseOf(context).Transaction(func(tx *gorm.DB) error {
+		err := fn(tx)
+		if err != nil {
+			return err
+		}
+
+		if context.IsAborted() {
+			return ContextAbortedError
+		} else if len(context.Errors) > errorCount {
+			return context.Errors.Last()
+		}
+
+		return nil
+	})
+}
+
+// transaction
+// inTransaction if transactional, otherwise calls fn with the database of context directly
+func (crud *Crud[T]) transaction(context *gin.Context, fn func(db *gorm.DB) error) error {
+	if !crud.transactional() {
+		return fn(crud.databaseOf(context))
+	}
+	return crud.inTransaction(context, fn)
+}
+
+// persist
+// saveRecord in a transaction if transactional
+func (crud *Crud[T]) persist(context *gin.Context, record *T, columns []string) (Code, error) {
+	var code Code
+	var saveErr error
+
+	var created bool
+
+	err := crud.transaction(context, func(db *gorm.DB) error {
+		created = crud.isNew(db, record)
+
+		if code, saveErr = crud.checkPrecondition(context, db, []Key{crud.keyOfRecord(record)}); saveErr != nil {
+			return saveErr
+		}
+
+		code, saveErr = crud.saveRecord(context, db, record, columns)
+		return saveErr
+	})
+	if saveErr != nil {
+		return code, saveErr
+	} else if err != nil {
+		crud.logger.Error().Printf("save: failed to commit record: %v", err)
+		return crud.Coder.InternalServerError(), errors.New("[error] save failed")
+	}
+
+	crud.InvalidateCache()
+	crud.publishSaved(created, record)
+	crud.notifyWebhook()
+
+	return "", nil
+}
+
 func (crud *Crud[T]) save(context *gin.Context) {
 	record := new(T)
-	err := context.ShouldBindJSON(record)
+	err := BindJSON(context, record)
 	if err != nil {
 		crud.error(context, crud.Coder.BadRequest(), "invalid body")
 		return
 	}
 
-	if crud.WillSave != nil {
-		if crud.WillSave(record, context, crud.database); context.IsAborted() {
+	code, err := crud.persist(context, record, nil)
+	if err != nil {
+		crud.saveError(context, code, err)
+		return
+	}
+
+	crud.ok(context, record)
+}
+
+func (crud *Crud[T]) batchSave(context *gin.Context) {
+	var records []T
+	err := BindJSON(context, &records)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), "invalid body")
+		return
+	}
+
+	if len(records) == 0 {
+		crud.ok(context, records)
+		return
+	}
+
+	var code Code
+	var errs BatchSaveErrors
+
+	created := make([]bool, len(records))
+
+	err = crud.inTransaction(context, func(tx *gorm.DB) error {
+		for i := range records {
+			created[i] = crud.isNew(tx, &records[i])
+			c, err := crud.saveRecord(context, tx, &records[i], nil)
+			if err != nil {
+				code = c
+				var fields FieldErrors
+				errors.As(err, &fields)
+				errs = append(errs, BatchSaveError{Index: i, Message: err.Error(), Fields: fields})
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if context.IsAborted() {
+			return
+		}
+		if len(errs) == 0 {
+			crud.logger.Error().Printf("batchSave: failed to commit records: %v", err)
+			crud.error(context, crud.Coder.InternalServerError(), "[error] save failed")
 			return
 		}
+		crud.errorWithData(context, code, errs)
+		return
+	}
+
+	ids := make([]Key, len(records))
+	for i := range records {
+		ids[i] = crud.keyOfRecord(&records[i])
 	}
+	crud.InvalidateCache()
+	for i := range records {
+		crud.publishSaved(created[i], &records[i])
+	}
+	crud.notifyWebhook()
+
+	crud.ok(context, records)
+}
 
-	err = crud.encensor(context, crud.database, record)
+// patchableColumnsOf
+// returns database columns of jsonFields, read-only fields such as primary keys or auto time fields are rejected
+func (crud *Crud[T]) patchableColumnsOf(jsonFields []string) ([]string, error) {
+	s, err := GetSchemaOf[T](crud.database)
 	if err != nil {
-		crud.logger.Error().Printf("save: failed to encensor record: %v", err)
-		crud.error(context, crud.Coder.InternalServerError(), "[error] encensor failed")
+		return nil, err
+	}
+
+	columns := make([]string, len(jsonFields))
+	for i, jsonField := range jsonFields {
+		objectFieldNames, err := GetObjectFieldNameOf[T](jsonField)
+		if err != nil {
+			return nil, err
+		}
+
+		field := s.LookUpField(objectFieldNames[0])
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("field %s not found", jsonField)
+		}
+
+		if field.PrimaryKey || !field.Updatable || field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 {
+			return nil, fmt.Errorf("field %s is read-only", jsonField)
+		}
+
+		columns[i] = field.DBName
+	}
+
+	return columns, nil
+}
+
+func (crud *Crud[T]) patch(context *gin.Context) {
+	id, ok := crud.keyOf(context, "id")
+	if !ok {
+		crud.error(context, crud.Coder.BadRequest(), "invalid id")
 		return
 	}
 
-	res := crud.database.Save(record)
-	if res.Error != nil {
-		crud.logger.Error().Printf("save: failed to save record: %v", res.Error)
-		crud.error(context, crud.Coder.InternalServerError(), "[error] save failed")
+	var body map[string]json.RawMessage
+	err := context.ShouldBindJSON(&body)
+	if err != nil || len(body) == 0 {
+		crud.error(context, crud.Coder.BadRequest(), "invalid body")
 		return
 	}
 
-	err = crud.decensor(context, crud.database, record)
+	jsonFields := slices.Sorted(maps.Keys(body))
+	if crud.OptimisticLock {
+		// lock field is a precondition, not a column to patch
+		jsonFields = slices.DeleteFunc(jsonFields, func(field string) bool {
+			return field == crud.lockJSONField
+		})
+	}
+
+	columns, err := crud.patchableColumnsOf(jsonFields)
 	if err != nil {
-		crud.logger.Error().Printf("save: failed to decensor record: %v", err)
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return
+	}
+
+	if crud.OptimisticLock && crud.VersionField != "" {
+		columns = append(columns, crud.lockColumn)
+	}
+
+	if len(columns) == 0 {
+		crud.error(context, crud.Coder.BadRequest(), "nothing to patch")
+		return
+	}
+
+	// records out of Tenancy or Policy.Scope are not found, instead of forbidden
+	db := crud.readable(context)
+	if db == nil {
+		return
+	}
+
+	record := new(T)
+	err = crud.whereKeys(db, id).First(record).Error
+	if err != nil {
+		crud.logger.Error().Printf("patch: failed to find record: %v", err)
+		crud.error(context, crud.Coder.NotFound(), "not found")
+		return
+	}
+
+	err = crud.decensor(context, crud.databaseOf(context), record)
+	if err != nil {
+		crud.logger.Error().Printf("patch: failed to decensor record: %v", err)
 		crud.error(context, crud.Coder.InternalServerError(), "[error] decensor failed")
 		return
 	}
 
-	if crud.DidSave != nil {
-		if crud.DidSave(record, context, res); context.IsAborted() {
-			return
-		}
+	content, err := json.Marshal(body)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), "invalid body")
+		return
+	}
+
+	err = json.Unmarshal(content, record)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), "invalid body")
+		return
+	}
+
+	code, err := crud.persist(context, record, columns)
+	if err != nil {
+		crud.saveError(context, code, err)
+		return
 	}
 
 	crud.ok(context, record)
@@ -362,25 +1276,138 @@ func (crud *Crud[T]) save(context *gin.Context) {
 func (crud *Crud[T]) delete(context *gin.Context) {
 	deleted := false
 
-	if crud.WillDelete != nil {
-		if crud.WillDelete(context, crud.database); context.IsAborted() {
-			return
+	ids := crud.keysOf(context, "id")
+	SetKeys(context, ids)
+
+	// matched before the delete, since hard deleted records can not be matched after it,
+	// and out of its transaction, so that matching does not hold it
+	subscribers := crud.subscribersOf(crud.databaseOf(context), ids)
+
+	err := crud.transaction(context, func(db *gorm.DB) error {
+		if code, err := crud.canDelete(context, db, ids); err != nil {
+			crud.error(context, code, err)
+			return ContextAbortedError
+		}
+
+		if code, err := crud.checkPrecondition(context, db, ids); err != nil {
+			crud.error(context, code, err)
+			return ContextAbortedError
 		}
-	}
 
-	if deleted = crud.OnDelete(context, crud.database); context.IsAborted() {
+		if crud.WillDelete != nil {
+			if crud.WillDelete(context, db); context.IsAborted() {
+				return ContextAbortedError
+			}
+		}
+
+		if crud.Audit == nil {
+			deleted = crud.OnDelete(context, db)
+		} else {
+			var err error
+			deleted, err = crud.audited(context, db, AuditOperationDelete, ids, crud.OnDelete)
+			if err != nil {
+				return err
+			}
+		}
+		if context.IsAborted() {
+			return ContextAbortedError
+		}
+
+		if deleted {
+			err := crud.enqueueWebhook(db, EventTypeDeleted, ids, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		if crud.DidDelete != nil {
+			if crud.DidDelete(context, db); context.IsAborted() {
+				return ContextAbortedError
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if !context.IsAborted() {
+			crud.logger.Error().Printf("delete: failed to delete records: %v", err)
+			crud.error(context, crud.Coder.InternalServerError(), "[error] delete failed")
+		}
 		return
 	}
 
-	if crud.DidDelete != nil {
-		if crud.DidDelete(context, crud.database); context.IsAborted() {
-			return
-		}
+	if deleted {
+		crud.InvalidateCache()
+		crud.publishTo(subscribers, EventTypeDeleted, ids, nil)
+		crud.notifyWebhook()
 	}
 
 	crud.ok(context, deleted)
 }
 
+func (crud *Crud[T]) restore(context *gin.Context) {
+	restored := false
+
+	ids := crud.keysOf(context, "ids")
+	SetKeys(context, ids)
+
+	err := crud.transaction(context, func(db *gorm.DB) error {
+		if code, err := crud.canDelete(context, db, ids); err != nil {
+			crud.error(context, code, err)
+			return ContextAbortedError
+		}
+
+		if crud.WillRestore != nil {
+			if crud.WillRestore(context, db); context.IsAborted() {
+				return ContextAbortedError
+			}
+		}
+
+		if crud.Audit == nil {
+			restored = crud.OnRestore(context, db)
+		} else {
+			var err error
+			restored, err = crud.audited(context, db, AuditOperationRestore, ids, crud.OnRestore)
+			if err != nil {
+				return err
+			}
+		}
+		if context.IsAborted() {
+			return ContextAbortedError
+		}
+
+		if restored {
+			err := crud.enqueueWebhook(db, EventTypeUpdated, ids, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		if crud.DidRestore != nil {
+			if crud.DidRestore(context, db); context.IsAborted() {
+				return ContextAbortedError
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if !context.IsAborted() {
+			crud.logger.Error().Printf("restore: failed to restore records: %v", err)
+			crud.error(context, crud.Coder.InternalServerError(), "[error] restore failed")
+		}
+		return
+	}
+
+	if restored {
+		crud.InvalidateCache()
+		crud.publish(EventTypeUpdated, ids, nil)
+		crud.notifyWebhook()
+	}
+
+	crud.ok(context, restored)
+}
+
 // endregion
 
 func Setup[T any](
@@ -402,6 +1429,11 @@ func Setup[T any](
 
 	crud.group = group
 	crud.database = database
+	crud.cachePrefix = group.BasePath() + ":"
+
+	if crud.QueryTimeout > 0 {
+		crud.group = group.Group("", QueryTimeoutHandler(crud.QueryTimeout))
+	}
 	crud.logger = logger
 
 	if crud.logger == nil {
@@ -413,12 +1445,29 @@ func Setup[T any](
 		crud.Coder = RestCoder
 	}
 
+	primaryKey, err := PrimaryKeyOf[T](crud.database)
+	if err != nil {
+		return err
+	}
+	crud.primaryKey = primaryKey
+
+	if crud.Validator == nil {
+		crud.Validator = DefaultValidator
+	}
+
 	if crud.GetCensors == nil {
 		crud.GetCensors = func(context *gin.Context, db *gorm.DB) ([]*censored.Censor, error) {
 			return nil, nil
 		}
 	}
 
+	if crud.Webhook != nil && crud.GetWebhookCensors != nil {
+		err = crud.Webhook.decensorWith(crud.auditModel(), crud.decensorWebhookRecord)
+		if err != nil {
+			return err
+		}
+	}
+
 	crud.DefaultPageSize = Ternary(
 		crud.DefaultPageSize <= 0,
 		DefaultPageSize,
@@ -434,31 +1483,103 @@ func Setup[T any](
 		crud.OnDelete = NewSoftDeleteHandler[T](crud.Coder)
 	}
 
+	if crud.OptimisticLock {
+		err := crud.setupLock()
+		if err != nil {
+			return err
+		}
+	}
+
+	if crud.Tenancy != nil {
+		var err error
+		crud.tenantColumn, err = tenantColumnOf[T](crud.database, crud.Tenancy)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(crud.FilterableFields) > 0 {
+		err := crud.setupFilter()
+		if err != nil {
+			return err
+		}
+	}
+
+	if crud.OnRestore == nil && IsSoftDeletable[T]() {
+		crud.OnRestore = NewRestoreHandler[T](crud.Coder)
+	}
+
 	if !crud.DisablePage {
 		crud.group.GET("/page/:pageNum/:pageSize", crud.page)
 		crud.group.POST("/page/:pageNum/:pageSize", crud.page)
 	}
 
+	if crud.EnablePagination {
+		crud.group.GET("/pagination/:pageNum/:pageSize", crud.pagination)
+		crud.group.POST("/pagination/:pageNum/:pageSize", crud.pagination)
+	}
+
+	if crud.EnableCursor {
+		crud.group.GET("/cursor/:pageSize", crud.cursor)
+		crud.group.POST("/cursor/:pageSize", crud.cursor)
+	}
+
 	if crud.EnableGetAll {
 		crud.group.GET("/all", crud.all)
 		crud.group.POST("/all", crud.all)
 	}
 
+	if crud.EnableExport {
+		crud.group.GET("/export/:format", crud.export)
+		crud.group.POST("/export/:format", crud.export)
+	}
+
 	if !crud.DisableCount {
 		crud.group.GET("/count", crud.count)
 		crud.group.POST("/count", crud.count)
 	}
 
 	if !crud.DisableGetOne {
-		crud.group.GET("/one/:id", crud.one)
+		crud.group.GET("/one"+crud.keyRoute("id"), crud.one)
 	}
 
 	if !crud.DisableSave {
 		crud.group.PUT("", crud.save)
 	}
 
+	if !crud.DisableBatchSave {
+		crud.group.PUT("/batch", crud.batchSave)
+	}
+
+	if crud.EnableImport {
+		crud.group.POST("/import/:format", crud.importRecords)
+	}
+
+	if crud.EnableEvents {
+		crud.events = newEventHub[T]()
+		go crud.dispatch()
+		// QueryTimeout would end the stream
+		group.GET("/events", crud.subscribe)
+	}
+
+	if !crud.DisablePatch {
+		crud.group.PATCH(crud.keyRoute("id"), crud.patch)
+	}
+
 	if !crud.DisableDelete {
-		crud.group.DELETE("/:id", crud.delete)
+		crud.group.DELETE(crud.keyRoute("id"), crud.delete)
+	}
+
+	if !crud.DisableRestore && crud.OnRestore != nil {
+		crud.group.POST("/restore"+crud.keyRoute("ids"), crud.restore)
+	}
+
+	if crud.Audit != nil {
+		crud.group.GET("/audit"+crud.keyRoute("id"), crud.auditHistory)
+	}
+
+	if crud.OpenAPI != nil {
+		crud.setupOpenAPI()
 	}
 
 	return nil
@@ -0,0 +1,117 @@
+package gocrud
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (crud *Crud[T]) auditModel() string {
+	return reflect.TypeFor[T]().Name()
+}
+
+func (crud *Crud[T]) auditRecordIDOf(record *T) string {
+	return FormatKey(crud.keyOfRecord(record))
+}
+
+// auditBeforeSave
+// returns the stored one of record, nil for new records
+func (crud *Crud[T]) auditBeforeSave(db *gorm.DB, record *T) (*T, error) {
+	id := crud.keyOfRecord(record)
+	if IsZeroKey(id) {
+		return nil, nil
+	}
+
+	stored := new(T)
+	err := crud.whereKeys(db.Model(new(T)), id).First(stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+func (crud *Crud[T]) auditRecordsOf(db *gorm.DB, ids []Key) (map[string]*T, error) {
+	var list []T
+	err := crud.whereKeys(db.Model(new(T)), ids...).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]*T, len(list))
+	for i := range list {
+		records[crud.auditRecordIDOf(&list[i])] = &list[i]
+	}
+
+	return records, nil
+}
+
+// audited
+// calls handler with db, which should be a transaction, and logs the records of ids before and after it
+func (crud *Crud[T]) audited(
+	context *gin.Context,
+	db *gorm.DB,
+	operation AuditOperation,
+	ids []Key,
+	handler func(context *gin.Context, db *gorm.DB) bool,
+) (bool, error) {
+	before, err := crud.auditRecordsOf(db, ids)
+	if err != nil {
+		return false, err
+	}
+
+	done := handler(context, db)
+	if context.IsAborted() {
+		return done, ContextAbortedError
+	}
+
+	after, err := crud.auditRecordsOf(db, ids)
+	if err != nil {
+		return done, err
+	}
+
+	for _, id := range ids {
+		recordID := FormatKey(id)
+		b, a := before[recordID], after[recordID]
+		if b == nil && a == nil {
+			continue
+		}
+		err = crud.Audit.Log(db, context, operation, crud.auditModel(), recordID, b, a)
+		if err != nil {
+			return done, err
+		}
+	}
+
+	return done, nil
+}
+
+// auditHistory
+// lists AuditLog of record `:id`, or `/:org_id/:code` of a composite key, the latest first
+func (crud *Crud[T]) auditHistory(context *gin.Context) {
+	id, ok := crud.keyOf(context, "id")
+	if !ok {
+		crud.error(context, crud.Coder.BadRequest(), "invalid id")
+		return
+	}
+
+	if crud.readable(context) == nil {
+		return
+	}
+	if code, err := crud.checkScope(context, crud.databaseOf(context), []Key{id}); err != nil {
+		crud.error(context, code, err)
+		return
+	}
+
+	logs, err := crud.Audit.History(crud.databaseOf(context), crud.auditModel(), FormatKey(id))
+	if err != nil {
+		crud.logger.Error().Printf("audit: failed to find logs: %v", err)
+		crud.error(context, crud.Coder.InternalServerError(), "[error] database failed")
+		return
+	}
+
+	crud.ok(context, logs)
+}
@@ -0,0 +1,79 @@
+package gocrud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cacheNamespaceOne   = "one:"
+	cacheNamespaceList  = "list:"
+	cacheNamespaceCount = "count:"
+)
+
+// cacheKey
+// returns the key of a read in namespace, which is scoped by search values, the tenant of context and CacheKey,
+// returns an empty key if the read should not be cached
+func (crud *Crud[T]) cacheKey(context *gin.Context, namespace string, parts ...any) string {
+	if crud.Cache == nil || crud.Policy != nil {
+		return ""
+	}
+
+	// preloaded associations are decensored in place, which would be shared by cached records
+	if len(GetExpandedPaths(context)) > 0 {
+		return ""
+	}
+
+	searches, err := GetSearchValuesFromContext(context)
+	if err != nil {
+		return ""
+	}
+
+	hasher := sha256.New()
+	_, _ = fmt.Fprintln(hasher, parts...)
+	_, _ = fmt.Fprintln(hasher, searches.Encode())
+	if tenant, ok := context.Get(ContextKeyTenant); ok {
+		_, _ = fmt.Fprintln(hasher, tenant)
+	}
+	if crud.CacheKey != nil {
+		_, _ = fmt.Fprintln(hasher, crud.CacheKey(context))
+	}
+
+	return fmt.Sprintf(
+		"%s%d:%s%s",
+		crud.cachePrefix, crud.cacheGeneration.Load(), namespace, hex.EncodeToString(hasher.Sum(nil)),
+	)
+}
+
+// oneCacheNamespace
+// namespace of `one` of id
+func oneCacheNamespace(id Key) string {
+	return fmt.Sprintf("%s%s:", cacheNamespaceOne, FormatKey(id))
+}
+
+func (crud *Crud[T]) cacheGet(key string) (any, bool) {
+	if key == "" {
+		return nil, false
+	}
+	return crud.Cache.Get(key)
+}
+
+func (crud *Crud[T]) cacheSet(key string, value any) {
+	if key == "" {
+		return
+	}
+	crud.Cache.Set(key, value)
+}
+
+// InvalidateCache
+// moves reads of this Crud to a new generation, so that all cached ones are missed,
+// missed entries are left to be evicted by Cache, such as by the capacity or the TTL of LRUCache
+func (crud *Crud[T]) InvalidateCache() {
+	if crud.Cache == nil {
+		return
+	}
+	crud.cacheGeneration.Add(1)
+}
@@ -0,0 +1,252 @@
+package gocrud
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SearchKeyCursor
+// reserved search key for the cursor returned by the previous page of `/cursor/:pageSize`
+const SearchKeyCursor = "cursor"
+
+var (
+	InvalidCursorError     = errors.New("invalid cursor")
+	UnsupportedCursorError = errors.New("only sorts of SortBy are supported by cursor")
+)
+
+// CursorPage
+// Next is empty if there is no more records
+type CursorPage[T any] struct {
+	List []T    `json:"list"`
+	Next string `json:"next,omitempty"`
+}
+
+type cursorKey struct {
+	Column string          `json:"c"`
+	Desc   bool            `json:"d,omitempty"`
+	Value  json.RawMessage `json:"v"`
+}
+
+func encodeCursor(keys []cursorKey) (string, error) {
+	content, err := json.Marshal(keys)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(content), nil
+}
+
+func decodeCursor(cursor string) ([]cursorKey, error) {
+	content, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, InvalidCursorError
+	}
+
+	var keys []cursorKey
+	err = json.Unmarshal(content, &keys)
+	if err != nil {
+		return nil, InvalidCursorError
+	}
+
+	return keys, nil
+}
+
+// cursorSortKeys
+// returns sorts applied by SortBy, followed by the primary key, which makes the order unique
+func (crud *Crud[T]) cursorSortKeys(context *gin.Context, db *gorm.DB) ([]SortKey, error) {
+	sortKeys := GetHandledSort(context)
+
+	if orderBy, ok := db.Statement.Clauses["ORDER BY"]; ok {
+		if expression, ok := orderBy.Expression.(clause.OrderBy); ok && len(expression.Columns) != len(sortKeys) {
+			return nil, UnsupportedCursorError
+		}
+	}
+
+	for _, column := range crud.primaryKey.Columns() {
+		if !slices.ContainsFunc(sortKeys, func(key SortKey) bool {
+			return key.Column == column
+		}) {
+			sortKeys = append(sortKeys, SortKey{Column: column})
+		}
+	}
+
+	return sortKeys, nil
+}
+
+// whereAfterCursor
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func (crud *Crud[T]) whereAfterCursor(db *gorm.DB, sortKeys []SortKey, cursor string) (*gorm.DB, error) {
+	keys, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) != len(sortKeys) {
+		return nil, InvalidCursorError
+	}
+
+	s, err := GetSchemaOf[T](crud.database)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		if key.Column != sortKeys[i].Column || key.Desc != sortKeys[i].Desc {
+			return nil, InvalidCursorError
+		}
+
+		field := s.LookUpField(key.Column)
+		if field == nil {
+			return nil, InvalidCursorError
+		}
+
+		value := reflect.New(field.FieldType)
+		err = json.Unmarshal(key.Value, value.Interface())
+		if err != nil {
+			return nil, InvalidCursorError
+		}
+		values[i] = value.Elem().Interface()
+	}
+
+	var ors []string
+	var args []any
+	for i, key := range keys {
+		var ands []string
+		for j := range i {
+			ands = append(ands, fmt.Sprintf("`%s` = ?", keys[j].Column))
+			args = append(args, values[j])
+		}
+		ands = append(ands, fmt.Sprintf("`%s` %s ?", key.Column, Ternary(key.Desc, OperatorLt, OperatorGt)))
+		args = append(args, values[i])
+
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return db.Where("("+strings.Join(ors, " OR ")+")", args...), nil
+}
+
+func (crud *Crud[T]) nextCursor(context *gin.Context, sortKeys []SortKey, record *T) (string, error) {
+	s, err := GetSchemaOf[T](crud.database)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]cursorKey, len(sortKeys))
+	for i, sortKey := range sortKeys {
+		field := s.LookUpField(sortKey.Column)
+		if field == nil {
+			return "", fmt.Errorf("field %s not found", sortKey.Column)
+		}
+
+		value, _ := field.ValueOf(context.Request.Context(), reflect.ValueOf(record).Elem())
+		content, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+
+		keys[i] = cursorKey{Column: sortKey.Column, Desc: sortKey.Desc, Value: content}
+	}
+
+	return encodeCursor(keys)
+}
+
+// cursor
+// keyset pagination, columns used for sorting should NOT be nullable
+func (crud *Crud[T]) cursor(context *gin.Context) {
+	pageSize, err := strconv.ParseUint(context.Param("pageSize"), 10, 64)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), "invalid page size")
+		return
+	}
+
+	if pageSize <= 0 || (crud.DisallowNonstandardPageSize && !slices.Contains(crud.PageSizes, pageSize)) {
+		pageSize = crud.DefaultPageSize
+	}
+
+	db := crud.readable(context)
+	if db == nil {
+		return
+	}
+
+	db, err = crud.handleSearches(context, db)
+	if err != nil {
+		crud.searchError(context, "cursor", err)
+		return
+	}
+
+	if crud.WillCursor != nil {
+		if db = crud.WillCursor(&pageSize, context, db); context.IsAborted() {
+			return
+		}
+	}
+
+	sortKeys, err := crud.cursorSortKeys(context, db)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return
+	}
+
+	searches, err := GetSearchValuesFromContext(context)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), "invalid search")
+		return
+	}
+
+	if cursor, ok := PickFirstValuableString(searches[SearchKeyCursor]); ok {
+		db, err = crud.whereAfterCursor(db, sortKeys, cursor)
+		if err != nil {
+			crud.error(context, crud.Coder.BadRequest(), err)
+			return
+		}
+	}
+
+	for _, sortKey := range sortKeys[len(GetHandledSort(context)):] {
+		// primary columns have been appended by cursorSortKeys
+		db = db.Order(fmt.Sprintf("`%s` ASC", sortKey.Column))
+	}
+
+	var list []T
+	err = db.Limit(int(pageSize + 1)).Find(&list).Error
+	if err != nil {
+		crud.logger.Error().Printf("cursor: failed to find records: %v", err)
+		crud.error(context, crud.Coder.InternalServerError(), "[error] database failed")
+		return
+	}
+
+	page := &CursorPage[T]{List: list}
+
+	if uint64(len(list)) > pageSize {
+		page.List = list[:pageSize]
+		page.Next, err = crud.nextCursor(context, sortKeys, &page.List[pageSize-1])
+		if err != nil {
+			crud.logger.Error().Printf("cursor: failed to encode cursor: %v", err)
+			crud.error(context, crud.Coder.InternalServerError(), "[error] cursor failed")
+			return
+		}
+	}
+
+	err = crud.decensorList(context, db, page.List)
+	if err != nil {
+		crud.logger.Error().Printf("cursor: failed to decensor records: %v", err)
+		crud.error(context, crud.Coder.InternalServerError(), "[error] decensor failed")
+		return
+	}
+
+	if crud.DidCursor != nil {
+		if crud.DidCursor(page, context, db); context.IsAborted() {
+			return
+		}
+	}
+
+	crud.ok(context, page)
+}
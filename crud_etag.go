@@ -0,0 +1,130 @@
+package gocrud
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var PreconditionFailedError = errors.New("record does not match If-Match")
+
+// etagOf
+// a strong entity tag of `"<key>-<updatedAt>"` if T has UpdatedAt, otherwise of a hash of record,
+// so that it can be compared strongly with If-Match
+func (crud *Crud[T]) etagOf(record *T) (string, error) {
+	updatedAt := reflect.ValueOf(record).Elem().FieldByName("UpdatedAt")
+	if updatedAt.IsValid() {
+		if t, ok := updatedAt.Interface().(time.Time); ok {
+			return StrongETag(fmt.Sprintf("%s-%d", FormatKey(crud.keyOfRecord(record)), t.UnixNano())), nil
+		}
+	}
+
+	hash, err := hashOf(record)
+	if err != nil {
+		return "", err
+	}
+	return StrongETag(hash), nil
+}
+
+// okWithETag
+// responds data with header ETag, or 304 without body if If-None-Match matches etag
+func (crud *Crud[T]) okWithETag(context *gin.Context, etag string, data any) {
+	context.Header(HeaderKeyETag, etag)
+
+	if ETagMatches(context.GetHeader(HeaderKeyIfNoneMatch), etag) {
+		context.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+
+	crud.ok(context, data)
+}
+
+// okWithRecordETag
+// okWithETag with etagOf record if EnableETag, or with a hash of record if `fields` or `expand` is applied,
+// since etagOf does not change with the selected fields or the preloaded associations
+func (crud *Crud[T]) okWithRecordETag(context *gin.Context, record *T) {
+	if !crud.EnableETag {
+		crud.ok(context, *record)
+		return
+	}
+
+	if _, selected := GetSelectedFields(context); selected || len(GetExpandedPaths(context)) > 0 {
+		crud.okWithHashETag(context, *record)
+		return
+	}
+
+	etag, err := crud.etagOf(record)
+	if err != nil {
+		crud.logger.Error().Printf("etag: failed to compute etag: %v", err)
+		crud.ok(context, *record)
+		return
+	}
+
+	crud.okWithETag(context, etag, *record)
+}
+
+// okWithHashETag
+// okWithETag with a hash of data if EnableETag
+func (crud *Crud[T]) okWithHashETag(context *gin.Context, data any) {
+	if !crud.EnableETag {
+		crud.ok(context, data)
+		return
+	}
+
+	etag, err := HashETagOf(data)
+	if err != nil {
+		crud.logger.Error().Printf("etag: failed to compute etag: %v", err)
+		crud.ok(context, data)
+		return
+	}
+
+	crud.okWithETag(context, etag, data)
+}
+
+// checkPrecondition
+// returns Coder.PreconditionFailed() unless every record of ids matches If-Match by the strong comparison,
+// nothing will be checked without If-Match or EnableETag
+func (crud *Crud[T]) checkPrecondition(context *gin.Context, db *gorm.DB, ids []Key) (Code, error) {
+	ifMatch := context.GetHeader(HeaderKeyIfMatch)
+	if !crud.EnableETag || ifMatch == "" {
+		return "", nil
+	}
+
+	if code, err := crud.checkScope(context, db, ids); err != nil {
+		return code, err
+	}
+
+	for _, id := range ids {
+		record := new(T)
+		err := crud.whereKeys(db.Model(new(T)), id).First(record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return crud.Coder.PreconditionFailed(), WithDetails(PreconditionFailedError, &ErrorDetails{IDs: []Key{id}})
+		} else if err != nil {
+			crud.logger.Error().Printf("etag: failed to find record: %v", err)
+			return crud.Coder.InternalServerError(), errors.New("[error] precondition failed")
+		}
+
+		err = crud.decensor(context, db, record)
+		if err != nil {
+			crud.logger.Error().Printf("etag: failed to decensor record: %v", err)
+			return crud.Coder.InternalServerError(), errors.New("[error] decensor failed")
+		}
+
+		etag, err := crud.etagOf(record)
+		if err != nil {
+			crud.logger.Error().Printf("etag: failed to compute etag: %v", err)
+			return crud.Coder.InternalServerError(), errors.New("[error] precondition failed")
+		}
+
+		if !ETagMatchesStrongly(ifMatch, etag) {
+			return crud.Coder.PreconditionFailed(), WithDetails(PreconditionFailedError, &ErrorDetails{IDs: []Key{id}})
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,278 @@
+package gocrud
+
+import (
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// eventStreamContentType
+// content type of server-sent events
+const eventStreamContentType = "text/event-stream"
+
+type EventType string
+
+const (
+	EventTypeCreated EventType = "created"
+	EventTypeUpdated EventType = "updated"
+	EventTypeDeleted EventType = "deleted"
+)
+
+// EventBufferSize
+// events are dropped if the buffer of a subscriber or the queue of a Crud is full
+var EventBufferSize = 64
+
+// Event
+// pushed by `/events` of Crud, the name of a server-sent event is Type
+type Event[T any] struct {
+	Type   EventType `json:"type"`
+	IDs    Keys      `json:"ids"`
+	Record *T        `json:"record,omitempty"`
+}
+
+type eventSubscriber[T any] struct {
+	// context
+	// a copy of the context of subscription, which holds its search values, tenant and request context
+	context *gin.Context
+	events  chan Event[T]
+}
+
+// queuedEvent
+// subscribers are matched before the change if it is not nil, such as of hard deleted records,
+// otherwise they are matched by dispatch
+type queuedEvent[T any] struct {
+	event       Event[T]
+	subscribers []*eventSubscriber[T]
+}
+
+type eventHub[T any] struct {
+	lock        sync.RWMutex
+	subscribers map[*eventSubscriber[T]]struct{}
+	// queue
+	// events are dispatched one by one in order of publishing
+	queue chan queuedEvent[T]
+	// done
+	// closed by close, which stops dispatch and ends streams of subscribers
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newEventHub[T any]() *eventHub[T] {
+	return &eventHub[T]{
+		subscribers: make(map[*eventSubscriber[T]]struct{}),
+		queue:       make(chan queuedEvent[T], EventBufferSize),
+		done:        make(chan struct{}),
+	}
+}
+
+func (h *eventHub[T]) close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+func (h *eventHub[T]) add(subscriber *eventSubscriber[T]) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.subscribers[subscriber] = struct{}{}
+}
+
+func (h *eventHub[T]) remove(subscriber *eventSubscriber[T]) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.subscribers, subscriber)
+}
+
+// snapshot
+// subscribers at the moment, so that matching them does not hold the lock
+func (h *eventHub[T]) snapshot() []*eventSubscriber[T] {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return slices.Collect(maps.Keys(h.subscribers))
+}
+
+// subscriptionOf
+// a query of any record of ids in the scope of subscriber and matching its search values,
+// soft deleted records are matched as well, false if subscriber is unsubscribing or its search values fail
+func (crud *Crud[T]) subscriptionOf(subscriber *eventSubscriber[T], db *gorm.DB, ids []Key) (*gorm.DB, bool) {
+	// search handlers may write to context, such as sorts
+	context := subscriber.context.Copy()
+	if context.Request.Context().Err() != nil {
+		// unsubscribing
+		return nil, false
+	}
+
+	db, err := crud.tenantScope(context, db.Model(new(T)).Unscoped())
+	if err != nil {
+		return nil, false
+	}
+	if crud.Policy != nil {
+		db = crud.Policy.Scope(db, context)
+	}
+
+	db, err = crud.handleSearches(context, db)
+	if err != nil {
+		return nil, false
+	}
+
+	return crud.whereKeys(db, ids...).Select("1").Limit(1), true
+}
+
+// subscribersOf
+// subscribers matched with ids in one query, see subscriptionOf, nil if EnableEvents is false.
+// db should not be in a transaction, so that matching does not hold it.
+func (crud *Crud[T]) subscribersOf(db *gorm.DB, ids []Key) []*eventSubscriber[T] {
+	if crud.events == nil {
+		return nil
+	}
+
+	var candidates []*eventSubscriber[T]
+	var columns []string
+	var subscriptions []any
+	for _, subscriber := range crud.events.snapshot() {
+		subscription, ok := crud.subscriptionOf(subscriber, db, ids)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, subscriber)
+		columns = append(columns, "(?) IS NOT NULL")
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	matched := make([]*eventSubscriber[T], 0, len(candidates))
+	if len(candidates) == 0 {
+		return matched
+	}
+
+	results := make([]bool, len(candidates))
+	dest := make([]any, len(candidates))
+	for i := range results {
+		dest[i] = &results[i]
+	}
+
+	err := db.Raw("SELECT "+strings.Join(columns, ", "), subscriptions...).Row().Scan(dest...)
+	if err != nil {
+		crud.logger.Error().Printf("events: failed to match records: %v", err)
+		return matched
+	}
+
+	for i, subscriber := range candidates {
+		if results[i] {
+			matched = append(matched, subscriber)
+		}
+	}
+	return matched
+}
+
+// dispatch
+// pushes queued events to matched subscribers, runs in background since Setup until CloseEvents
+func (crud *Crud[T]) dispatch() {
+	for {
+		var queued queuedEvent[T]
+		select {
+		case <-crud.events.done:
+			return
+		case queued = <-crud.events.queue:
+		}
+
+		subscribers := queued.subscribers
+		if subscribers == nil {
+			subscribers = crud.subscribersOf(crud.database, queued.event.IDs)
+		}
+		for _, subscriber := range subscribers {
+			select {
+			case subscriber.events <- queued.event:
+			default:
+				crud.logger.Warn().Printf("events: buffer of subscriber is full, %s event of %v is dropped", queued.event.Type, queued.event.IDs)
+			}
+		}
+	}
+}
+
+// CloseEvents
+// stops dispatching events and ends streams of subscribers, events published after it are dropped
+func (crud *Crud[T]) CloseEvents() {
+	if crud.events != nil {
+		crud.events.close()
+	}
+}
+
+// publish
+// queues an event of ids for subscribers, does nothing if EnableEvents is false
+func (crud *Crud[T]) publish(eventType EventType, ids []Key, record *T) {
+	crud.publishTo(nil, eventType, ids, record)
+}
+
+// publishTo
+// publish to subscribers matched before the change, see subscribersOf, or to the ones matched by dispatch if nil
+func (crud *Crud[T]) publishTo(subscribers []*eventSubscriber[T], eventType EventType, ids []Key, record *T) {
+	if crud.events == nil || len(ids) == 0 {
+		return
+	}
+
+	event := Event[T]{Type: eventType, IDs: ids}
+	if crud.EventsWithRecord && record != nil {
+		copied := *record
+		event.Record = &copied
+	}
+
+	select {
+	case <-crud.events.done:
+	case crud.events.queue <- queuedEvent[T]{event: event, subscribers: subscribers}:
+	default:
+		crud.logger.Warn().Printf("events: queue is full, %s event of %v is dropped", eventType, ids)
+	}
+}
+
+// publishSaved
+// publishes created or updated of record, created is true if record was new before saved
+func (crud *Crud[T]) publishSaved(created bool, record *T) {
+	crud.publish(Ternary(created, EventTypeCreated, EventTypeUpdated), []Key{crud.keyOfRecord(record)}, record)
+}
+
+// subscribe
+// streams events until the client disconnects, search values of the request filter events
+func (crud *Crud[T]) subscribe(context *gin.Context) {
+	db := crud.readable(context)
+	if db == nil {
+		return
+	}
+
+	// search values are validated once here, and applied to every event
+	if _, err := crud.handleSearches(context.Copy(), db); err != nil {
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return
+	}
+
+	subscriber := &eventSubscriber[T]{
+		context: context.Copy(),
+		events:  make(chan Event[T], EventBufferSize),
+	}
+
+	crud.events.add(subscriber)
+	defer crud.events.remove(subscriber)
+
+	context.Header("Content-Type", eventStreamContentType)
+	context.Header("Cache-Control", "no-cache")
+	context.Header("Connection", "keep-alive")
+	context.Status(http.StatusOK)
+	context.Writer.Flush()
+
+	context.Stream(func(_ io.Writer) bool {
+		select {
+		case <-context.Request.Context().Done():
+			return false
+		case <-crud.events.done:
+			return false
+		case event := <-subscriber.events:
+			context.SSEvent(string(event.Type), event)
+			return true
+		}
+	})
+}
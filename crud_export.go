@@ -0,0 +1,236 @@
+package gocrud
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV    ExportFormat = "csv"
+	ExportFormatNDJSON ExportFormat = "ndjson"
+)
+
+// DefaultExportFlushSize
+// rows written between two flushes of response
+const DefaultExportFlushSize = 100
+
+var UnsupportedExportFormatError = errors.New("unsupported export format")
+
+var exportContentTypes = map[ExportFormat]string{
+	ExportFormatCSV:    "text/csv; charset=utf-8",
+	ExportFormatNDJSON: "application/x-ndjson; charset=utf-8",
+}
+
+// exportColumnsOf
+// json field names of database columns, in the order of struct fields,
+// only selected fields will be returned if search key `fields` is applied
+func (crud *Crud[T]) exportColumnsOf(context *gin.Context) ([]string, error) {
+	s, err := GetSchemaOf[T](crud.database)
+	if err != nil {
+		return nil, err
+	}
+
+	selectedFields, selected := GetSelectedFields(context)
+
+	var columns []string
+	for _, field := range reflect.VisibleFields(reflect.TypeFor[T]()) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		if selected && !slices.Contains(selectedFields, field.Name) {
+			continue
+		}
+
+		if schemaField := s.LookUpField(field.Name); schemaField == nil || schemaField.DBName == "" {
+			continue
+		}
+
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+
+		name := strings.TrimSpace(strings.Split(jsonTag, ",")[0])
+		if name == "" {
+			name = field.Name
+		}
+
+		if !slices.Contains(columns, name) {
+			columns = append(columns, name)
+		}
+	}
+
+	return columns, nil
+}
+
+// csvCellOf
+// strings are unquoted, null is empty, others are kept as json
+func csvCellOf(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+	return string(raw), nil
+}
+
+func csvRecordOf(record any, columns []string) ([]string, error) {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(content, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		cells[i], err = csvCellOf(fields[column])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cells, nil
+}
+
+// export
+// streams records row by row, so that memory usage stays flat on large tables
+func (crud *Crud[T]) export(context *gin.Context) {
+	format := ExportFormat(context.Param("format"))
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		crud.error(context, crud.Coder.BadRequest(), UnsupportedExportFormatError)
+		return
+	}
+
+	db := crud.readable(context)
+	if db == nil {
+		return
+	}
+
+	db, err := crud.handleSearches(context, db)
+	if err != nil {
+		crud.searchError(context, "export", err)
+		return
+	}
+
+	if crud.WillExport != nil {
+		if db = crud.WillExport(context, db); context.IsAborted() {
+			return
+		}
+	}
+
+	db, err = crud.selectFields(context, db)
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return
+	}
+
+	columns, err := crud.exportColumnsOf(context)
+	if err != nil {
+		crud.logger.Error().Printf("export: failed to get columns: %v", err)
+		crud.error(context, crud.Coder.InternalServerError(), "[error] schema failed")
+		return
+	}
+
+	rows, err := db.Rows()
+	if err != nil {
+		crud.logger.Error().Printf("export: failed to query records: %v", err)
+		crud.error(context, crud.Coder.InternalServerError(), "[error] database failed")
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	name := strings.ToLower(reflect.TypeFor[T]().Name())
+	context.Header("Content-Type", contentType)
+	context.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	context.Status(http.StatusOK)
+
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+
+	switch format {
+	case ExportFormatCSV:
+		csvWriter = csv.NewWriter(context.Writer)
+		err = csvWriter.Write(columns)
+	case ExportFormatNDJSON:
+		jsonEncoder = json.NewEncoder(context.Writer)
+	}
+
+	flush := func() error {
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		context.Writer.Flush()
+		return nil
+	}
+
+	var count int64
+	for err == nil && rows.Next() {
+		var record T
+		err = db.ScanRows(rows, &record)
+		if err != nil {
+			break
+		}
+
+		err = crud.decensor(context, db, &record)
+		if err != nil {
+			break
+		}
+
+		if csvWriter != nil {
+			var cells []string
+			cells, err = csvRecordOf(&record, columns)
+			if err == nil {
+				err = csvWriter.Write(cells)
+			}
+		} else {
+			err = jsonEncoder.Encode(&record)
+		}
+
+		count++
+		if err == nil && count%DefaultExportFlushSize == 0 {
+			err = flush()
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil {
+		// response has been partially written, nothing can be responded anymore
+		crud.logger.Error().Printf("export: failed to export records: %v", err)
+		context.Abort()
+		return
+	}
+
+	if crud.DidExport != nil {
+		crud.DidExport(count, context, db)
+	}
+}
@@ -0,0 +1,353 @@
+package gocrud
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// QueryKeyDryRun
+// query key of `/import/:format`, such as `/import/csv?dryRun=true`
+const QueryKeyDryRun = "dryRun"
+
+const importSavePoint = "gocrud_import"
+
+var (
+	UnsupportedImportFormatError = errors.New("unsupported import format")
+
+	importDryRunError = errors.New("dry run")
+)
+
+type ImportLineError struct {
+	Line    int         `json:"line"`
+	Message string      `json:"message"`
+	Fields  FieldErrors `json:"fields,omitempty"`
+}
+
+// ImportReport
+// Total is the count of non-empty lines excluding the csv header
+type ImportReport struct {
+	DryRun   bool              `json:"dryRun"`
+	Total    int64             `json:"total"`
+	Imported int64             `json:"imported"`
+	Errors   []ImportLineError `json:"errors"`
+}
+
+// importReader
+// returns io.EOF as err if there is no more lines,
+// lineErr is the error of the current line only, the following lines are still readable
+type importReader[T any] func() (line int, record *T, lineErr error, err error)
+
+// jsonValueOfCSVCell
+// cells of string fields are always quoted, others are kept as json if possible, such as numbers and booleans
+func jsonValueOfCSVCell(field reflect.StructField, cell string) (json.RawMessage, error) {
+	t := field.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.String && json.Valid([]byte(cell)) {
+		return json.RawMessage(cell), nil
+	}
+
+	return json.Marshal(cell)
+}
+
+// newCSVImportReader
+// header names are json field names of T, empty cells are left as zero values
+func newCSVImportReader[T any](body io.Reader) (importReader[T], error) {
+	reader := csv.NewReader(body)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+
+	jsonFields := JSONFieldsOf[T]()
+
+	fields := make([]reflect.StructField, len(header))
+	for i, name := range header {
+		field, ok := jsonFields[name]
+		if !ok {
+			return nil, fmt.Errorf("field %s not found", name)
+		}
+		fields[i] = field
+	}
+
+	return func() (int, *T, error, error) {
+		cells, err := reader.Read()
+		if err != nil {
+			var parseError *csv.ParseError
+			if errors.As(err, &parseError) {
+				return parseError.StartLine, nil, parseError.Err, nil
+			}
+			return 0, nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]json.RawMessage)
+		for i, cell := range cells {
+			if cell == "" {
+				continue
+			}
+			values[header[i]], err = jsonValueOfCSVCell(fields[i], cell)
+			if err != nil {
+				return line, nil, err, nil
+			}
+		}
+
+		content, err := json.Marshal(values)
+		if err != nil {
+			return line, nil, err, nil
+		}
+
+		record := new(T)
+		err = json.Unmarshal(content, record)
+		if err != nil {
+			return line, nil, err, nil
+		}
+
+		return line, record, nil, nil
+	}, nil
+}
+
+// newNDJSONImportReader
+// empty lines are skipped
+func newNDJSONImportReader[T any](body io.Reader) importReader[T] {
+	reader := bufio.NewReader(body)
+	line := 0
+
+	return func() (int, *T, error, error) {
+		for {
+			content, err := reader.ReadBytes('\n')
+			if err != nil && (err != io.EOF || len(content) == 0) {
+				return 0, nil, nil, err
+			}
+
+			line++
+
+			content = bytes.TrimSpace(content)
+			if len(content) == 0 {
+				continue
+			}
+
+			record := new(T)
+			err = json.Unmarshal(content, record)
+			if err != nil {
+				return line, nil, err, nil
+			}
+
+			return line, record, nil, nil
+		}
+	}
+}
+
+// importLineWriter
+// discards the response written by hooks to the context of an import line,
+// what they responded is taken from the error of saveRecord, see abortedErrorOf
+type importLineWriter struct {
+	header http.Header
+	status int
+	size   int
+}
+
+func newImportLineWriter() *importLineWriter {
+	return &importLineWriter{header: http.Header{}, status: http.StatusOK, size: -1}
+}
+
+func (w *importLineWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *importLineWriter) Write(content []byte) (int, error) {
+	w.WriteHeaderNow()
+	w.size += len(content)
+	return len(content), nil
+}
+
+func (w *importLineWriter) WriteString(content string) (int, error) {
+	return w.Write([]byte(content))
+}
+
+func (w *importLineWriter) WriteHeader(status int) {
+	if status > 0 && !w.Written() {
+		w.status = status
+	}
+}
+
+func (w *importLineWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+	}
+}
+
+func (w *importLineWriter) Status() int {
+	return w.status
+}
+
+func (w *importLineWriter) Size() int {
+	return w.size
+}
+
+func (w *importLineWriter) Written() bool {
+	return w.size != -1
+}
+
+func (w *importLineWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *importLineWriter) Flush() {}
+
+func (w *importLineWriter) CloseNotify() <-chan bool {
+	return nil
+}
+
+func (w *importLineWriter) Pusher() http.Pusher {
+	return nil
+}
+
+// importContextOf
+// a context of one line, sharing the request, params and keys of context, and writing to an importLineWriter,
+// so that a hook aborting it or responding an error with it fails the line only
+func importContextOf(context *gin.Context) *gin.Context {
+	return &gin.Context{
+		Request: context.Request,
+		Writer:  newImportLineWriter(),
+		Params:  context.Params,
+		Keys:    maps.Clone(context.Keys),
+	}
+}
+
+// importMessageOf
+// message of err, or of what a hook responded if it aborted the context of the line
+func importMessageOf(err error, lineContext *gin.Context) string {
+	var responseError *ResponseError
+	if errors.As(err, &responseError) {
+		return responseError.Message
+	} else if !errors.Is(err, ContextAbortedError) {
+		return err.Error()
+	} else if last := lineContext.Errors.Last(); last != nil {
+		return last.Error()
+	}
+	return http.StatusText(lineContext.Writer.Status())
+}
+
+// importRecords
+// saves every line in a save point of one transaction, lines those failed are reported and skipped,
+// including the ones rejected by hooks, which are called with a context of the line, see importContextOf.
+// The whole transaction will be rolled back in dry run mode, hooks are called in both modes.
+func (crud *Crud[T]) importRecords(context *gin.Context) {
+	var next importReader[T]
+	var err error
+
+	switch ExportFormat(context.Param("format")) {
+	case ExportFormatCSV:
+		next, err = newCSVImportReader[T](context.Request.Body)
+	case ExportFormatNDJSON:
+		next = newNDJSONImportReader[T](context.Request.Body)
+	default:
+		err = UnsupportedImportFormatError
+	}
+	if err != nil {
+		crud.error(context, crud.Coder.BadRequest(), err)
+		return
+	}
+
+	report := &ImportReport{Errors: []ImportLineError{}}
+
+	if dryRun := context.Query(QueryKeyDryRun); dryRun != "" {
+		report.DryRun, err = strconv.ParseBool(dryRun)
+		if err != nil {
+			crud.error(context, crud.Coder.BadRequest(), "invalid dry run")
+			return
+		}
+	}
+
+	// imported records and whether they were new, published once committed
+	var imported []*T
+	var created []bool
+
+	err = crud.databaseOf(context).Transaction(func(tx *gorm.DB) error {
+		for {
+			line, record, lineErr, err := next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+
+			report.Total++
+
+			if lineErr != nil {
+				report.Errors = append(report.Errors, ImportLineError{Line: line, Message: lineErr.Error()})
+				continue
+			}
+
+			err = tx.SavePoint(importSavePoint).Error
+			if err != nil {
+				return err
+			}
+
+			lineContext := importContextOf(context)
+
+			isNew := crud.isNew(tx, record)
+			_, err = crud.saveRecord(lineContext, tx, record, nil)
+			if err == nil && len(lineContext.Errors) > 0 {
+				err = lineContext.Errors.Last()
+			}
+			if err != nil {
+				var fields FieldErrors
+				errors.As(err, &fields)
+				report.Errors = append(report.Errors, ImportLineError{Line: line, Message: importMessageOf(err, lineContext), Fields: fields})
+
+				err = tx.RollbackTo(importSavePoint).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			report.Imported++
+			imported = append(imported, record)
+			created = append(created, isNew)
+		}
+
+		if report.DryRun {
+			return importDryRunError
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, importDryRunError) {
+		if context.IsAborted() {
+			return
+		}
+		crud.logger.Error().Printf("import: failed to import records: %v", err)
+		crud.error(context, crud.Coder.InternalServerError(), "[error] import failed")
+		return
+	}
+
+	if !report.DryRun {
+		crud.InvalidateCache()
+		for i := range imported {
+			crud.publishSaved(created[i], imported[i])
+		}
+		crud.notifyWebhook()
+	}
+
+	crud.ok(context, report)
+}
@@ -0,0 +1,177 @@
+package gocrud
+
+import (
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+)
+
+// searchKeys
+// keys of SearchHandlers, and reserved search keys those are enabled
+func (crud *Crud[T]) searchKeys() []string {
+	keys := slices.Collect(maps.Keys(crud.SearchHandlers))
+	if len(crud.SelectableFields) > 0 {
+		keys = append(keys, SearchKeyFields)
+	}
+	if len(crud.Preloads) > 0 {
+		keys = append(keys, SearchKeyExpand)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// keyTypes
+// types of path params of keyRoute, for routes of a single key
+func (crud *Crud[T]) keyTypes(name string) map[string]reflect.Type {
+	params := crud.keyParams(name)
+	types := make(map[string]reflect.Type, len(params))
+	for i, param := range params {
+		types[param] = crud.primaryKey.Fields[i].FieldType
+	}
+	return types
+}
+
+// setupOpenAPI
+// documents routes registered by Setup
+func (crud *Crud[T]) setupOpenAPI() {
+	tag := openAPITagOf(crud.group)
+	searchKeys := crud.searchKeys()
+
+	add := func(methods []string, relativePath, summary string, route OpenAPIRoute) {
+		for _, method := range methods {
+			route.Method = method
+			route.Path = openAPIPathOf(crud.group, relativePath)
+			route.Tag = tag
+			route.Summary = summary
+			crud.OpenAPI.Add(route)
+		}
+	}
+
+	searchMethods := []string{http.MethodGet, http.MethodPost}
+
+	// conditional
+	// headers of conditional requests, see EnableETag
+	conditional := func(key string) []string {
+		if crud.EnableETag {
+			return []string{key}
+		}
+		return nil
+	}
+
+	if !crud.DisablePage {
+		add(searchMethods, "/page/:pageNum/:pageSize", "page", OpenAPIRoute{
+			SearchKeys: searchKeys,
+			HeaderKeys: conditional(HeaderKeyIfNoneMatch),
+			Data:       reflect.TypeFor[[]T](),
+		})
+	}
+
+	if crud.EnablePagination {
+		add(searchMethods, "/pagination/:pageNum/:pageSize", "page with total", OpenAPIRoute{
+			SearchKeys: searchKeys,
+			HeaderKeys: conditional(HeaderKeyIfNoneMatch),
+			Data:       reflect.TypeFor[Pagination[T]](),
+		})
+	}
+
+	if crud.EnableCursor {
+		add(searchMethods, "/cursor/:pageSize", "page by cursor", OpenAPIRoute{
+			SearchKeys: append(slices.Clone(searchKeys), SearchKeyCursor),
+			Data:       reflect.TypeFor[CursorPage[T]](),
+		})
+	}
+
+	if crud.EnableGetAll {
+		add(searchMethods, "/all", "all", OpenAPIRoute{
+			SearchKeys: searchKeys,
+			HeaderKeys: conditional(HeaderKeyIfNoneMatch),
+			Data:       reflect.TypeFor[[]T](),
+		})
+	}
+
+	if crud.EnableExport {
+		add(searchMethods, "/export/:format", "export as csv or ndjson", OpenAPIRoute{
+			SearchKeys:   searchKeys,
+			ContentTypes: []string{exportContentTypes[ExportFormatCSV], exportContentTypes[ExportFormatNDJSON]},
+		})
+	}
+
+	if !crud.DisableCount {
+		add(searchMethods, "/count", "count", OpenAPIRoute{
+			SearchKeys: searchKeys,
+			Data:       reflect.TypeFor[int64](),
+		})
+	}
+
+	if !crud.DisableGetOne {
+		add([]string{http.MethodGet}, "/one"+crud.keyRoute("id"), "one", OpenAPIRoute{
+			SearchKeys: slices.DeleteFunc(slices.Clone(searchKeys), func(key string) bool {
+				return key != SearchKeyFields && key != SearchKeyExpand
+			}),
+			HeaderKeys: conditional(HeaderKeyIfNoneMatch),
+			KeyTypes:   crud.keyTypes("id"),
+			Data:       reflect.TypeFor[T](),
+		})
+	}
+
+	if !crud.DisableSave {
+		add([]string{http.MethodPut}, "", "save", OpenAPIRoute{
+			HeaderKeys: conditional(HeaderKeyIfMatch),
+			Body:       reflect.TypeFor[T](),
+			Data:       reflect.TypeFor[T](),
+		})
+	}
+
+	if !crud.DisableBatchSave {
+		add([]string{http.MethodPut}, "/batch", "save in batch", OpenAPIRoute{
+			Body: reflect.TypeFor[[]T](),
+			Data: reflect.TypeFor[[]T](),
+		})
+	}
+
+	if crud.EnableImport {
+		add([]string{http.MethodPost}, "/import/:format", "import csv or ndjson", OpenAPIRoute{
+			QueryKeys:        []string{QueryKeyDryRun},
+			BodyContentTypes: []string{exportContentTypes[ExportFormatCSV], exportContentTypes[ExportFormatNDJSON]},
+			Data:             reflect.TypeFor[ImportReport](),
+		})
+	}
+
+	if !crud.DisablePatch {
+		add([]string{http.MethodPatch}, crud.keyRoute("id"), "patch", OpenAPIRoute{
+			HeaderKeys: conditional(HeaderKeyIfMatch),
+			KeyTypes:   crud.keyTypes("id"),
+			Body:       reflect.TypeFor[map[string]any](),
+			Data:       reflect.TypeFor[T](),
+		})
+	}
+
+	// keys of delete and restore are comma separated, so they are strings
+	if !crud.DisableDelete {
+		add([]string{http.MethodDelete}, crud.keyRoute("id"), "delete", OpenAPIRoute{
+			HeaderKeys: conditional(HeaderKeyIfMatch),
+			Data:       reflect.TypeFor[bool](),
+		})
+	}
+
+	if !crud.DisableRestore && crud.OnRestore != nil {
+		add([]string{http.MethodPost}, "/restore"+crud.keyRoute("ids"), "restore", OpenAPIRoute{
+			Data: reflect.TypeFor[bool](),
+		})
+	}
+
+	if crud.EnableEvents {
+		add([]string{http.MethodGet}, "/events", "stream changes as server-sent events", OpenAPIRoute{
+			SearchKeys:   searchKeys,
+			ContentTypes: []string{eventStreamContentType},
+		})
+	}
+
+	if crud.Audit != nil {
+		add([]string{http.MethodGet}, "/audit"+crud.keyRoute("id"), "audit history", OpenAPIRoute{
+			KeyTypes: crud.keyTypes("id"),
+			Data:     reflect.TypeFor[[]AuditLog](),
+		})
+	}
+}
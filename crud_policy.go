@@ -0,0 +1,119 @@
+package gocrud
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var ForbiddenError = errors.New("forbidden")
+
+// Policy
+// authorization of every operation of Crud, a non-nil error denies the operation with Coder.Forbidden(),
+// and the error will be responded as the message.
+type Policy[T any] interface {
+	// CanRead
+	// for all, page, pagination, cursor, count, one, export and audit
+	CanRead(context *gin.Context, db *gorm.DB) error
+	// CanWrite
+	// for every record of save, batch save, patch and import, called after WillSave
+	CanWrite(record *T, context *gin.Context, db *gorm.DB) error
+	// CanDelete
+	// for delete and restore
+	CanDelete(context *gin.Context, db *gorm.DB) error
+	// Scope
+	// row level filtering, such as `db.Where("owner_id = ?", userID)`,
+	// records out of scope can not be read, and can not be written or deleted by id
+	Scope(db *gorm.DB, context *gin.Context) *gorm.DB
+}
+
+// readable
+// returns db of T scoped by Tenancy and Policy, nil if reading is denied
+func (crud *Crud[T]) readable(context *gin.Context) *gorm.DB {
+	db, err := crud.tenantScope(context, crud.databaseOf(context).Model(new(T)))
+	if err != nil {
+		crud.error(context, crud.Coder.Forbidden(), err)
+		return nil
+	}
+
+	if crud.Policy == nil {
+		return db
+	}
+
+	err = crud.Policy.CanRead(context, db)
+	if err != nil {
+		crud.error(context, crud.Coder.Forbidden(), err)
+		return nil
+	}
+
+	return crud.Policy.Scope(db, context)
+}
+
+// checkScope
+// returns ForbiddenError if any of ids exists but is out of Tenancy or Policy.Scope, new ids are allowed
+func (crud *Crud[T]) checkScope(context *gin.Context, db *gorm.DB, ids []Key) (Code, error) {
+	if (crud.Policy == nil && crud.Tenancy == nil) || len(ids) == 0 {
+		return "", nil
+	}
+
+	// soft deleted records are counted as well, for restoring
+	scoped, err := crud.tenantScope(context, db.Model(new(T)).Unscoped())
+	if err != nil {
+		return crud.Coder.Forbidden(), err
+	}
+	if crud.Policy != nil {
+		scoped = crud.Policy.Scope(scoped, context)
+	}
+
+	var total, inScope int64
+
+	err = crud.whereKeys(db.Model(new(T)).Unscoped(), ids...).Count(&total).Error
+	if err == nil {
+		err = crud.whereKeys(scoped, ids...).Count(&inScope).Error
+	}
+	if err != nil {
+		crud.logger.Error().Printf("policy: failed to count records: %v", err)
+		return crud.Coder.InternalServerError(), errors.New("[error] scope failed")
+	}
+
+	if inScope != total {
+		return crud.Coder.Forbidden(), ForbiddenError
+	}
+
+	return "", nil
+}
+
+// canWrite
+// stamps the tenant on record, then checks it with Policy
+func (crud *Crud[T]) canWrite(context *gin.Context, db *gorm.DB, record *T) (Code, error) {
+	err := crud.stampTenant(context, record)
+	if err != nil {
+		return crud.Coder.Forbidden(), err
+	}
+
+	if crud.Policy != nil {
+		err = crud.Policy.CanWrite(record, context, db)
+		if err != nil {
+			return crud.Coder.Forbidden(), err
+		}
+	}
+
+	var ids []Key
+	if id := crud.keyOfRecord(record); !IsZeroKey(id) {
+		ids = append(ids, id)
+	}
+
+	return crud.checkScope(context, db, ids)
+}
+
+func (crud *Crud[T]) canDelete(context *gin.Context, db *gorm.DB, ids []Key) (Code, error) {
+	if crud.Policy != nil {
+		err := crud.Policy.CanDelete(context, db)
+		if err != nil {
+			return crud.Coder.Forbidden(), err
+		}
+	}
+
+	return crud.checkScope(context, db, ids)
+}
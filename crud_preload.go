@@ -0,0 +1,136 @@
+package gocrud
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/allape/gocensored"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SearchKeyExpand
+// reserved search key for preloading associations, see Crud.Preloads
+const SearchKeyExpand = "expand"
+
+const ContextKeyExpandedPaths = "gocrud:crud:expandedpaths"
+
+func GetExpandedPaths(context *gin.Context) []string {
+	return context.GetStringSlice(ContextKeyExpandedPaths)
+}
+
+func SetExpandedPaths(context *gin.Context, paths []string) {
+	context.Set(ContextKeyExpandedPaths, paths)
+}
+
+// expand
+// applies `expand` of search values as associations to preload
+func (crud *Crud[T]) expand(context *gin.Context, db *gorm.DB) (*gorm.DB, error) {
+	searches, err := GetSearchValuesFromContext(context)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := PickFirstValuableString(searches[SearchKeyExpand])
+	if !ok {
+		return db, nil
+	}
+
+	var paths []string
+	for _, name := range RemoveDuplication(StringArrayFromCommaSeparatedString(value)) {
+		path, ok := crud.Preloads[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is not expandable", name)
+		}
+		paths = append(paths, path)
+		db = db.Preload(path)
+	}
+
+	SetExpandedPaths(context, paths)
+
+	return db, nil
+}
+
+// associationTree
+// `A.B` and `A.C` will be merged into `A: {B, C}`, so that every association will be censored only once
+type associationTree map[string]associationTree
+
+func newAssociationTree(paths []string) associationTree {
+	tree := associationTree{}
+	for _, path := range paths {
+		node := tree
+		for name := range strings.SplitSeq(path, ".") {
+			if _, ok := node[name]; !ok {
+				node[name] = associationTree{}
+			}
+			node = node[name]
+		}
+	}
+	return tree
+}
+
+// DecensorAssociations
+// decensors preloaded associations of record, paths are the ones passed to gorm.DB.Preload
+func DecensorAssociations(censors []*censored.Censor, record any, paths []string) error {
+	if len(censors) == 0 || len(paths) == 0 {
+		return nil
+	}
+	return decensorAssociationTree(censors, reflect.ValueOf(record), newAssociationTree(paths))
+}
+
+func decensorAssociationTree(censors []*censored.Censor, value reflect.Value, tree associationTree) error {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for name, subtree := range tree {
+		field := value.FieldByName(name)
+		if !field.IsValid() {
+			continue
+		}
+
+		var elements []reflect.Value
+		switch field.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := range field.Len() {
+				elements = append(elements, field.Index(i))
+			}
+		default:
+			elements = append(elements, field)
+		}
+
+		for _, element := range elements {
+			for element.Kind() == reflect.Pointer {
+				if element.IsNil() {
+					break
+				}
+				element = element.Elem()
+			}
+			if element.Kind() != reflect.Struct || !element.CanAddr() {
+				continue
+			}
+
+			for _, censor := range censors {
+				err := censor.Decensor(element.Addr().Interface())
+				if err != nil {
+					return err
+				}
+			}
+
+			err := decensorAssociationTree(censors, element, subtree)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
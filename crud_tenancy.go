@@ -0,0 +1,51 @@
+package gocrud
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// tenantScope
+// filters db by the tenant of context, db is returned as it is if Tenancy is nil
+func (crud *Crud[T]) tenantScope(context *gin.Context, db *gorm.DB) (*gorm.DB, error) {
+	if crud.Tenancy == nil {
+		return db, nil
+	}
+
+	tenant, err := crud.Tenancy.Tenant(context)
+	if err != nil {
+		return nil, err
+	}
+
+	return crud.Tenancy.Scope(db, crud.tenantColumn, tenant), nil
+}
+
+// stampTenant
+// overrides the tenant field of record with the tenant of context
+func (crud *Crud[T]) stampTenant(context *gin.Context, record *T) error {
+	if crud.Tenancy == nil {
+		return nil
+	}
+
+	tenant, err := crud.Tenancy.Tenant(context)
+	if err != nil {
+		return err
+	}
+
+	return crud.Tenancy.Stamp(record, tenant)
+}
+
+// checkTenant
+// returns CrossTenantError if the tenant field of record has been changed from the tenant of context
+func (crud *Crud[T]) checkTenant(context *gin.Context, record *T) error {
+	if crud.Tenancy == nil {
+		return nil
+	}
+
+	tenant, err := crud.Tenancy.Tenant(context)
+	if err != nil {
+		return err
+	}
+
+	return crud.Tenancy.Check(record, tenant)
+}
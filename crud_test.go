@@ -1,9 +1,15 @@
 package gocrud
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"path"
 	"slices"
@@ -35,11 +41,34 @@ type UserTag struct {
 	TagID  ID `json:"tagId" gorm:"primaryKey"`
 }
 
+type UniqueTag struct {
+	Base
+	Name string `json:"name" gorm:"uniqueIndex"`
+}
+
+type VersionedTag struct {
+	Base
+	Name    string `json:"name"`
+	Version int64  `json:"version"`
+}
+
 type SecretUser struct {
 	Base
 	Name string `json:"name" censored:"aes.base64"`
 }
 
+type Company struct {
+	Base
+	Name    string          `json:"name"`
+	Secrets []CompanySecret `json:"secrets"`
+}
+
+type CompanySecret struct {
+	Base
+	CompanyID ID     `json:"companyId"`
+	Content   string `json:"content" censored:"aes.base64"`
+}
+
 func basicSetup(databaseName string) (*gorm.DB, *gin.Engine, error) {
 	if databaseName == "" {
 		databaseName = "test.db"
@@ -515,55 +544,1991 @@ func TestSecretUser(t *testing.T) {
 	}
 }
 
-//goland:noinspection GoUnusedFunction
-func testRunCrudServer(t *testing.T) {
-	db, engine, err := basicSetup("TestStartServer.db")
+func TestBatchSave(t *testing.T) {
+	db, engine, err := basicSetup("TestBatchSave.db")
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
-		EnableGetAll: true,
-		SearchHandlers: BaseSearchHandlers(SearchHandlers{
-			"like_name": KeywordLike("name", nil),
-			"name":      KeywordEqual("name", nil),
-		}),
+		WillSave: func(record *User, context *gin.Context, db *gorm.DB) {
+			if strings.Contains(record.Name, "freak") {
+				MakeErrorResponse(context, RestCoder.BadRequest(), "freak is not allowed")
+				return
+			}
+		},
+		DidSave: func(record *User, context *gin.Context, db *gorm.DB) {
+			if record.Age < 0 {
+				MakeErrorResponse(context, RestCoder.BadRequest(), "age can not be negative")
+				return
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&UniqueTag{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/unique-tag"), db, nil, &Crud[UniqueTag]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crud.NewAddress(4)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := crudy.BatchSave([]User{
+		{Name: "test1", Age: 1},
+		{Name: "test2", Age: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(users))
+	} else if users[0].ID != 1 || users[1].ID != 2 {
+		t.Fatalf("expected ids 1 and 2, got %d and %d", users[0].ID, users[1].ID)
+	}
+
+	// WillSave aborted at index 1
+	_, err = crudy.BatchSave([]User{
+		{Name: "test3"},
+		{Name: "freak"},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	// DidSave aborted at index 2, the updated record at index 0 should be rolled back too
+	_, err = crudy.BatchSave([]User{
+		{Base: Base{ID: 1}, Name: "test1-edited"},
+		{Name: "test4"},
+		{Name: "test5", Age: -1},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	count, err := crudy.Count(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 2 {
+		t.Fatalf("expected 2 users, got %d", count)
+	}
+
+	u1, err := crudy.One(1)
+	if err != nil {
+		t.Fatal(err)
+	} else if u1.Name != "test1" {
+		t.Fatalf("expected test1, got %s", u1.Name)
+	}
+
+	// per-index error list
+	res := new(R[BatchSaveErrors])
+	err = MakeJSONRequest(http.DefaultClient, nil, mustBeURL(addr+"/unique-tag/batch"), http.MethodPut, mustBeReader([]UniqueTag{
+		{Name: "tag1"},
+		{Name: "tag2"},
+		{Name: "tag1"},
+	}), res)
+	if err == nil {
+		t.Fatal("expected error")
+	} else if len(res.Data) != 1 {
+		t.Fatalf("expected 1 error, got %d", len(res.Data))
+	} else if res.Data[0].Index != 2 {
+		t.Fatalf("expected error at index 2, got %d", res.Data[0].Index)
+	}
+}
+
+func TestDeleteAndRestore(t *testing.T) {
+	db, engine, err := basicSetup("TestDeleteAndRestore.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := 0
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		SearchHandlers: BaseSearchHandlers(),
+		WillRestore: func(context *gin.Context, db *gorm.DB) {
+			if context.Param("ids") == "404" {
+				MakeErrorResponse(context, RestCoder.NotFound(), "not found")
+				return
+			}
+		},
+		DidRestore: func(context *gin.Context, db *gorm.DB) {
+			restored++
+		},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	err = Setup(engine.Group("/tag"), db, nil, &Crud[Tag]{
-		EnableGetAll: true,
-		SearchHandlers: BaseSearchHandlers(SearchHandlers{
-			"like_name": KeywordLike("name", nil),
-			"name":      KeywordEqual("name", nil),
-		}),
+		OnDelete: NewHardDeleteHandler[Tag](RestCoder),
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = SetupM2MConnectorController[UserTag](
-		engine.Group("/user-tag"), db, gogger.New("controller:user-tag"),
-		"UserID", "TagID",
-		nil,
-	)
+	var binding = address.crud.NewAddress(5)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	static := engine.Group("/static")
-	err = NewHttpFileSystemController(static, TestDataDir, &HttpFileSystemConfig{
-		AllowUpload: true,
+	_, err = crudy.BatchSave([]User{{Name: "test1"}, {Name: "test2"}, {Name: "test3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := crudy.DeleteMany(ToKeys[ID](1, 2))
+	if err != nil {
+		t.Fatal(err)
+	} else if !deleted {
+		t.Fatal("response is not true")
+	}
+
+	count, err := crudy.Count(SearchParams{"deleted": "false"})
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 1 {
+		t.Fatalf("expected 1, got %d", count)
+	}
+
+	restoredOk, err := crudy.Restore(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if !restoredOk {
+		t.Fatal("response is not true")
+	} else if restored != 1 {
+		t.Fatalf("expected DidRestore to be called once, got %d", restored)
+	}
+
+	restoredOk, err = crudy.Restore(3)
+	if err != nil {
+		t.Fatal(err)
+	} else if restoredOk {
+		t.Fatal("record 3 is not deleted, response should be false")
+	}
+
+	_, err = crudy.Restore(404)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	count, err = crudy.Count(SearchParams{"deleted": "false"})
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 3 {
+		t.Fatalf("expected 3, got %d", count)
+	}
+
+	tagCrudy, err := NewCrudy[Tag](addr + "/tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tagCrudy.BatchSave([]Tag{{Name: "tag1"}, {Name: "tag2"}, {Name: "tag3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err = tagCrudy.DeleteMany(ToKeys[ID](1, 3))
+	if err != nil {
+		t.Fatal(err)
+	} else if !deleted {
+		t.Fatal("response is not true")
+	}
+
+	count, err = tagCrudy.Count(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 1 {
+		t.Fatalf("expected 1, got %d", count)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	db, engine, err := basicSetup("TestPatch.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		WillSave: func(record *User, context *gin.Context, db *gorm.DB) {
+			if strings.Contains(record.Name, "freak") {
+				MakeErrorResponse(context, RestCoder.BadRequest(), "freak is not allowed")
+				return
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	censor, err := censored.NewDefaultCensor(&censored.Config{
+		Password: []byte("123456789_0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/secret-user"), db, nil, &Crud[SecretUser]{
+		GetCensors: func(_ *gin.Context, _ *gorm.DB) ([]*censored.Censor, error) {
+			return []*censored.Censor{censor}, nil
+		},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	var binding = address.crud.NewAddress(6)
+	var addr = "http://" + binding
+
 	go func() {
-		_ = engine.Run(address.crud.NewAddress(0))
+		_ = engine.Run(binding)
 	}()
 
-	Wait4CtrlC()
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = crudy.Save(&User{Name: "test1", Age: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u1, err := crudy.Patch(1, map[string]any{"age": 11})
+	if err != nil {
+		t.Fatal(err)
+	} else if u1.Name != "test1" {
+		t.Fatalf("expected test1, got %s", u1.Name)
+	} else if u1.Age != 11 {
+		t.Fatalf("expected 11, got %d", u1.Age)
+	}
+
+	u1, err = crudy.One(1)
+	if err != nil {
+		t.Fatal(err)
+	} else if u1.Name != "test1" {
+		t.Fatalf("expected test1, got %s", u1.Name)
+	} else if u1.Age != 11 {
+		t.Fatalf("expected 11, got %d", u1.Age)
+	}
+
+	for _, fields := range []map[string]any{
+		{"id": 2},
+		{"createdAt": time.Now()},
+		{"field_not_found": "test"},
+		{"name": "freak"},
+		{},
+	} {
+		_, err = crudy.Patch(1, fields)
+		if err == nil {
+			t.Fatalf("expected error for %v", fields)
+		}
+		t.Logf("got error: %v", err)
+	}
+
+	_, err = crudy.Patch(404, map[string]any{"age": 1})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	secretCrudy, err := NewCrudy[SecretUser](addr + "/secret-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = secretCrudy.Save(&SecretUser{Name: "I am a freak", Base: Base{Priority: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretUser, err := secretCrudy.Patch(1, map[string]any{"priority": 2})
+	if err != nil {
+		t.Fatal(err)
+	} else if secretUser.Name != "I am a freak" {
+		t.Fatalf("expected decensored name, got %s", secretUser.Name)
+	}
+
+	secretUser, err = secretCrudy.One(1)
+	if err != nil {
+		t.Fatal(err)
+	} else if secretUser.Name != "I am a freak" {
+		t.Fatalf("expected decensored name, got %s", secretUser.Name)
+	} else if secretUser.Priority != 2 {
+		t.Fatalf("expected 2, got %d", secretUser.Priority)
+	}
+}
+
+func TestOptimisticLock(t *testing.T) {
+	db, engine, err := basicSetup("TestOptimisticLock.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&VersionedTag{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		OptimisticLock: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/versioned-tag"), db, nil, &Crud[VersionedTag]{
+		OptimisticLock: true,
+		VersionField:   "Version",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/invalid-version"), db, nil, &Crud[VersionedTag]{
+		OptimisticLock: true,
+		VersionField:   "Name",
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	var binding = address.crud.NewAddress(7)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u1, err := crudy.Save(&User{Name: "test1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := *u1
+
+	u1.Name = "test1-edited"
+	u1, err = crudy.Save(u1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conflictError *ConflictError
+
+	stale.Name = "test1-stale"
+	_, err = crudy.Save(&stale)
+	if !errors.As(err, &conflictError) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+
+	_, err = crudy.Patch(1, map[string]any{"age": 1, "updatedAt": stale.UpdatedAt})
+	if !errors.As(err, &conflictError) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+
+	u1, err = crudy.Patch(1, map[string]any{"age": 2, "updatedAt": u1.UpdatedAt})
+	if err != nil {
+		t.Fatal(err)
+	} else if u1.Name != "test1-edited" {
+		t.Fatalf("expected test1-edited, got %s", u1.Name)
+	}
+
+	// without lock field
+	_, err = crudy.Patch(1, map[string]any{"age": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagCrudy, err := NewCrudy[VersionedTag](addr + "/versioned-tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := tagCrudy.Save(&VersionedTag{Name: "tag1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag.Name = "tag1-edited"
+	tag, err = tagCrudy.Save(tag)
+	if err != nil {
+		t.Fatal(err)
+	} else if tag.Version != 1 {
+		t.Fatalf("expected version 1, got %d", tag.Version)
+	}
+
+	_, err = tagCrudy.Save(&VersionedTag{Base: Base{ID: tag.ID}, Name: "tag1-stale", Version: 0})
+	if !errors.As(err, &conflictError) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+
+	tag, err = tagCrudy.Patch(tag.ID, map[string]any{"name": "tag1-patched"})
+	if err != nil {
+		t.Fatal(err)
+	} else if tag.Version != 2 {
+		t.Fatalf("expected version 2, got %d", tag.Version)
+	}
+
+	_, err = tagCrudy.Patch(tag.ID, map[string]any{"name": "tag1-stale", "version": 1})
+	if !errors.As(err, &conflictError) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+
+	tag, err = tagCrudy.One(tag.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if tag.Name != "tag1-patched" {
+		t.Fatalf("expected tag1-patched, got %s", tag.Name)
+	} else if tag.Version != 2 {
+		t.Fatalf("expected version 2, got %d", tag.Version)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	db, engine, err := basicSetup("TestCursor.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		EnableCursor: true,
+		SearchHandlers: BaseSearchHandlers(SearchHandlers{
+			"orderBy_age": SortBy("age"),
+			"age_gte":     KeywordStatement("age", OperatorGte, NumericValidate),
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crud.NewAddress(8)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := make([]User, 25)
+	for i := range users {
+		users[i] = User{Name: fmt.Sprintf("test%d", i), Age: i % 4}
+	}
+	_, err = crudy.BatchSave(users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []ID
+	var lastAge = 4
+	for user, err := range crudy.Iterate(10, SearchParams{"orderBy_age": "desc"}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Age > lastAge {
+			t.Fatalf("expected age <= %d, got %d", lastAge, user.Age)
+		}
+		lastAge = user.Age
+		ids = append(ids, user.ID)
+	}
+	if len(ids) != 25 {
+		t.Fatalf("expected 25 users, got %d", len(ids))
+	} else if len(RemoveDuplication(ids)) != 25 {
+		t.Fatal("expected no duplicated user")
+	}
+
+	ids = nil
+	for user, err := range crudy.Iterate(3, SearchParams{"age_gte": "3"}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, user.ID)
+	}
+	if len(ids) != 6 {
+		t.Fatalf("expected 6 users, got %d", len(ids))
+	} else if !slices.IsSorted(ids) {
+		t.Fatalf("expected ids sorted, got %v", ids)
+	}
+
+	page, err := crudy.Cursor("", 20, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.List) != 20 {
+		t.Fatalf("expected 20 users, got %d", len(page.List))
+	} else if page.Next == "" {
+		t.Fatal("expected next cursor")
+	}
+
+	// sort mismatched
+	_, err = crudy.Cursor(page.Next, 20, SearchParams{"orderBy_age": "asc"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	_, err = crudy.Cursor("I am an invalid cursor", 20, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	// sort not from SortBy
+	_, err = crudy.Cursor("", 20, SearchParams{"sortByPriorityThenUpdatedAt": "true"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	page, err = crudy.Cursor(page.Next, 20, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.List) != 5 {
+		t.Fatalf("expected 5 users, got %d", len(page.List))
+	} else if page.Next != "" {
+		t.Fatal("expected no next cursor")
+	}
+}
+
+func TestPagination(t *testing.T) {
+	db, engine, err := basicSetup("TestPagination.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		EnablePagination: true,
+		SearchHandlers: BaseSearchHandlers(SearchHandlers{
+			"orderBy_age": SortBy("age"),
+			"age_gte":     KeywordStatement("age", OperatorGte, NumericValidate),
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crud.NewAddress(9)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := make([]User, 25)
+	for i := range users {
+		users[i] = User{Name: fmt.Sprintf("test%d", i), Age: i}
+	}
+	_, err = crudy.BatchSave(users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pagination, err := crudy.Pagination(1, 10, SearchParams{"age_gte": "5", "orderBy_age": "desc"})
+	if err != nil {
+		t.Fatal(err)
+	} else if pagination.Total != 20 {
+		t.Fatalf("expected total 20, got %d", pagination.Total)
+	} else if len(pagination.List) != 10 {
+		t.Fatalf("expected 10 users, got %d", len(pagination.List))
+	} else if pagination.List[0].Age != 24 {
+		t.Fatalf("expected age 24, got %d", pagination.List[0].Age)
+	} else if pagination.PageNum != 1 || pagination.PageSize != 10 {
+		t.Fatalf("expected page 1 of size 10, got %d of %d", pagination.PageNum, pagination.PageSize)
+	} else if !pagination.HasNext {
+		t.Fatal("expected next page")
+	}
+
+	pagination, err = crudy.Pagination(2, 10, SearchParams{"age_gte": "5"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(pagination.List) != 10 {
+		t.Fatalf("expected 10 users, got %d", len(pagination.List))
+	} else if pagination.HasNext {
+		t.Fatal("expected no next page")
+	}
+
+	pagination, err = crudy.Pagination(0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if pagination.Total != 25 {
+		t.Fatalf("expected total 25, got %d", pagination.Total)
+	} else if pagination.PageNum != 1 || pagination.PageSize != DefaultPageSize {
+		t.Fatalf("expected page 1 of size %d, got %d of %d", DefaultPageSize, pagination.PageNum, pagination.PageSize)
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	db, engine, err := basicSetup("TestSelectFields.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	censor, err := censored.NewDefaultCensor(&censored.Config{
+		Password: []byte("123456789_0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[SecretUser]{
+		EnableGetAll:     true,
+		EnablePagination: true,
+		SelectableFields: []string{"name", "priority"},
+		GetCensors: func(_ *gin.Context, _ *gorm.DB) ([]*censored.Censor, error) {
+			return []*censored.Censor{censor}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(0)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[SecretUser](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = crudy.Save(&SecretUser{Name: "I am a freak", Base: Base{Priority: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := new(R[SecretUser])
+	err = MakeJSONRequest(http.DefaultClient, nil, mustBeURL(addr+"/user/one/1?fields=priority"), http.MethodGet, nil, res)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Data.ID != 1 {
+		t.Fatalf("expected id 1, got %d", res.Data.ID)
+	} else if res.Data.Priority != 1 {
+		t.Fatalf("expected priority 1, got %d", res.Data.Priority)
+	} else if res.Data.Name != "" {
+		t.Fatalf("expected empty name, got %s", res.Data.Name)
+	} else if !res.Data.CreatedAt.IsZero() {
+		t.Fatalf("expected zero created at, got %v", res.Data.CreatedAt)
+	}
+
+	all, err := crudy.All(SearchParams{"fields": "name"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(all) != 1 {
+		t.Fatalf("expected 1, got %d", len(all))
+	} else if all[0].Name != "I am a freak" {
+		t.Fatalf("expected decensored name, got %s", all[0].Name)
+	} else if all[0].Priority != 0 {
+		t.Fatalf("expected priority 0, got %d", all[0].Priority)
+	}
+
+	pagination, err := crudy.Pagination(1, 10, SearchParams{"fields": "name,priority"})
+	if err != nil {
+		t.Fatal(err)
+	} else if pagination.Total != 1 {
+		t.Fatalf("expected total 1, got %d", pagination.Total)
+	} else if pagination.List[0].Name != "I am a freak" || pagination.List[0].Priority != 1 {
+		t.Fatalf("unexpected record %v", pagination.List[0])
+	}
+
+	_, err = crudy.Page(1, 10, SearchParams{"fields": "name,createdAt"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPreloads(t *testing.T) {
+	db, engine, err := basicSetup("TestPreloads.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&Company{}, &CompanySecret{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	censor, err := censored.NewDefaultCensor(&censored.Config{
+		Password: []byte("123456789_0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getCensors := func(_ *gin.Context, _ *gorm.DB) ([]*censored.Censor, error) {
+		return []*censored.Censor{censor}, nil
+	}
+
+	err = Setup(engine.Group("/company"), db, nil, &Crud[Company]{
+		EnableGetAll: true,
+		Preloads: map[string]string{
+			"secrets": "Secrets",
+		},
+		GetCensors: getCensors,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/company-secret"), db, nil, &Crud[CompanySecret]{
+		GetCensors: getCensors,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(1)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	companyCrudy, err := NewCrudy[Company](addr + "/company")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretCrudy, err := NewCrudy[CompanySecret](addr + "/company-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	company, err := companyCrudy.Save(&Company{Name: "ACME"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = secretCrudy.Save(&CompanySecret{CompanyID: company.ID, Content: "the password is 123456"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored CompanySecret
+	err = db.First(&stored).Error
+	if err != nil {
+		t.Fatal(err)
+	} else if stored.Content == "the password is 123456" {
+		t.Fatal("expected encrypted content in database")
+	}
+
+	one, err := companyCrudy.One(company.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(one.Secrets) != 0 {
+		t.Fatalf("expected no secrets without expand, got %d", len(one.Secrets))
+	}
+
+	res := new(R[Company])
+	err = MakeJSONRequest(http.DefaultClient, nil, mustBeURL(fmt.Sprintf("%s/company/one/%d?expand=secrets", addr, company.ID)), http.MethodGet, nil, res)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Data.Secrets) != 1 {
+		t.Fatalf("expected 1 secret, got %d", len(res.Data.Secrets))
+	} else if res.Data.Secrets[0].Content != "the password is 123456" {
+		t.Fatalf("expected decensored content, got %s", res.Data.Secrets[0].Content)
+	}
+
+	all, err := companyCrudy.All(SearchParams{"expand": "secrets"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(all) != 1 || len(all[0].Secrets) != 1 {
+		t.Fatalf("expected 1 company with 1 secret, got %v", all)
+	} else if all[0].Secrets[0].Content != "the password is 123456" {
+		t.Fatalf("expected decensored content, got %s", all[0].Secrets[0].Content)
+	}
+
+	page, err := companyCrudy.Page(1, 10, SearchParams{"expand": "secrets"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page) != 1 || len(page[0].Secrets) != 1 {
+		t.Fatalf("expected 1 company with 1 secret, got %v", page)
+	}
+
+	_, err = companyCrudy.All(SearchParams{"expand": "owners"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestExport(t *testing.T) {
+	db, engine, err := basicSetup("TestExport.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	censor, err := censored.NewDefaultCensor(&censored.Config{
+		Password: []byte("123456789_0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exported int64
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[SecretUser]{
+		EnableExport:     true,
+		SelectableFields: []string{"name"},
+		SearchHandlers: BaseSearchHandlers(SearchHandlers{
+			"sort_priority": SortBy("priority"),
+		}),
+		GetCensors: func(_ *gin.Context, _ *gorm.DB) ([]*censored.Censor, error) {
+			return []*censored.Censor{censor}, nil
+		},
+		DidExport: func(count int64, _ *gin.Context, _ *gorm.DB) {
+			exported = count
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(2)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[SecretUser](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, name := range []string{"a, b", "c \"d\"", "e"} {
+		_, err = crudy.Save(&SecretUser{Name: name, Base: Base{Priority: int64(3 - i)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buffer bytes.Buffer
+	err = crudy.Export(ExportFormatCSV, SearchParams{"sort_priority": "asc"}, &buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(records))
+	} else if !slices.Contains(records[0], "name") || !slices.Contains(records[0], "priority") {
+		t.Fatalf("unexpected header %v", records[0])
+	} else if exported != 3 {
+		t.Fatalf("expected 3 exported, got %d", exported)
+	}
+
+	nameIndex := slices.Index(records[0], "name")
+	for i, name := range []string{"e", "c \"d\"", "a, b"} {
+		if records[i+1][nameIndex] != name {
+			t.Fatalf("expected %s, got %s", name, records[i+1][nameIndex])
+		}
+	}
+
+	buffer.Reset()
+	err = crudy.Export(ExportFormatCSV, SearchParams{"fields": "name", "in_id": "1"}, &buffer)
+	if err != nil {
+		t.Fatal(err)
+	} else if buffer.String() != "id,name\n1,\"a, b\"\n" {
+		t.Fatalf("unexpected csv %q", buffer.String())
+	}
+
+	buffer.Reset()
+	err = crudy.Export(ExportFormatNDJSON, nil, &buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+
+	var user SecretUser
+	err = json.Unmarshal([]byte(lines[0]), &user)
+	if err != nil {
+		t.Fatal(err)
+	} else if user.Name != "a, b" {
+		t.Fatalf("expected decensored name, got %s", user.Name)
+	}
+
+	err = crudy.Export("xml", nil, &buffer)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestImport(t *testing.T) {
+	db, engine, err := basicSetup("TestImport.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&UniqueTag{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/tag"), db, nil, &Crud[UniqueTag]{
+		EnableImport: true,
+		WillSave: func(record *UniqueTag, context *gin.Context, _ *gorm.DB) {
+			if record.Name == "reserved" {
+				MakeErrorResponse(context, RestCoder.BadRequest(), "name reserved is not allowed")
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(3)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[UniqueTag](addr + "/tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "name,priority\na,1\nb,2,3\na,3\nc,high\n\"d\",\n"
+
+	assertReport := func(report *ImportReport, total, imported int64, lines ...int) {
+		if report.Total != total {
+			t.Fatalf("expected total %d, got %d", total, report.Total)
+		} else if report.Imported != imported {
+			t.Fatalf("expected imported %d, got %d", imported, report.Imported)
+		} else if len(report.Errors) != len(lines) {
+			t.Fatalf("expected %d errors, got %v", len(lines), report.Errors)
+		}
+		for i, line := range lines {
+			if report.Errors[i].Line != line {
+				t.Fatalf("expected error at line %d, got %d", line, report.Errors[i].Line)
+			}
+		}
+	}
+
+	report, err := crudy.Import(ExportFormatCSV, strings.NewReader(content), true)
+	if err != nil {
+		t.Fatal(err)
+	} else if !report.DryRun {
+		t.Fatal("expected dry run")
+	}
+	assertReport(report, 5, 2, 3, 4, 5)
+
+	count, err := crudy.Count(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 0 {
+		t.Fatalf("expected nothing imported in dry run, got %d", count)
+	}
+
+	report, err = crudy.Import(ExportFormatCSV, strings.NewReader(content), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReport(report, 5, 2, 3, 4, 5)
+
+	all, err := crudy.Page(1, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(all) != 2 {
+		t.Fatalf("expected 2, got %d", len(all))
+	} else if all[0].Name != "a" || all[0].Priority != 1 || all[1].Name != "d" {
+		t.Fatalf("unexpected records %v", all)
+	}
+
+	report, err = crudy.Import(ExportFormatNDJSON, strings.NewReader("{\"name\":\"e\"}\n\nnot json\n{\"name\":\"a\"}"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReport(report, 3, 1, 3, 4)
+
+	count, err = crudy.Count(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 3 {
+		t.Fatalf("expected 3, got %d", count)
+	}
+
+	// a line rejected by WillSave is reported, and the others are still imported
+	report, err = crudy.Import(ExportFormatNDJSON, strings.NewReader("{\"name\":\"f\"}\n{\"name\":\"reserved\"}\n{\"name\":\"g\"}"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReport(report, 3, 2, 2)
+	if report.Errors[0].Message != "name reserved is not allowed" {
+		t.Fatalf("expected message of WillSave, got %s", report.Errors[0].Message)
+	}
+
+	count, err = crudy.Count(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 5 {
+		t.Fatalf("expected 5, got %d", count)
+	}
+
+	_, err = crudy.Import(ExportFormatCSV, strings.NewReader("name,color\nf,red\n"), false)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	db, engine, err := basicSetup("TestFilter.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		EnableGetAll:     true,
+		FilterableFields: []string{"name", "age"},
+		SearchHandlers: SearchHandlers{
+			"sort_age": SortBy("age"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(4)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, name := range []string{"alice", "bob", "carol", "dave"} {
+		_, err = crudy.Save(&User{Name: name, Age: 10 * (i + 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// name = alice OR (age > 25 AND NOT name LIKE d%)
+	filter := FilterNode{Or: []FilterNode{
+		{Field: "name", Op: OperatorEqual, Value: "alice"},
+		{And: []FilterNode{
+			{Field: "age", Op: OperatorGt, Value: 25},
+			{Not: &FilterNode{Field: "name", Op: OperatorLike, Value: "d%"}},
+		}},
+	}}
+
+	users, err := crudy.All(SearchParams{SearchKeyFilter: filter.String(), "sort_age": "asc"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(users) != 2 || users[0].Name != "alice" || users[1].Name != "carol" {
+		t.Fatalf("unexpected users %v", users)
+	}
+
+	count, err := crudy.Count(SearchParams{SearchKeyFilter: `{"field": "age", "op": "BETWEEN", "value": [20, 30]}`})
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 2 {
+		t.Fatalf("expected 2, got %d", count)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		SearchKeyFilter: FilterNode{Field: "name", Op: OperatorIn, Value: []string{"bob", "dave"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := new(R[[]User])
+	err = MakeJSONRequest(http.DefaultClient, nil, mustBeURL(addr+"/user/all"), http.MethodPost, bytes.NewReader(body), res)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Data) != 2 {
+		t.Fatalf("expected 2, got %d", len(res.Data))
+	}
+
+	for _, filter := range []string{
+		`{"field": "id", "op": "=", "value": 1}`,
+		`{"field": "name", "op": "=", "value": [1]}`,
+		`not json`,
+	} {
+		_, err = crudy.All(SearchParams{SearchKeyFilter: filter})
+		var responseError *ResponseError
+		if !errors.As(err, &responseError) || responseError.Code != RestCoder.BadRequest() {
+			t.Fatalf("expected bad request of %s, got %v", filter, err)
+		}
+
+		_, err = crudy.Count(SearchParams{SearchKeyFilter: filter})
+		if !errors.As(err, &responseError) || responseError.Code != RestCoder.BadRequest() {
+			t.Fatalf("expected bad request of %s, got %v", filter, err)
+		}
+	}
+}
+
+func TestTransactional(t *testing.T) {
+	db, engine, err := basicSetup("TestTransactional.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		Transactional: true,
+		DidSave: func(record *User, context *gin.Context, db *gorm.DB) {
+			err := db.Create(&Tag{Name: record.Name}).Error
+			if err != nil {
+				_ = context.Error(err)
+				return
+			}
+			if record.Name == "rollback" {
+				_ = context.Error(errors.New("rollback"))
+			}
+		},
+		DidDelete: func(context *gin.Context, db *gorm.DB) {
+			err := db.Where("name = ?", context.Param("id")).Delete(&Tag{}).Error
+			if err != nil {
+				_ = context.Error(err)
+				return
+			}
+			if context.Param("id") == "2" {
+				_ = context.Error(errors.New("rollback"))
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(5)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	countOf := func(model any) int64 {
+		var count int64
+		if err := db.Model(model).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	_, err = crudy.Save(&User{Name: "commit"})
+	if err != nil {
+		t.Fatal(err)
+	} else if countOf(&User{}) != 1 || countOf(&Tag{}) != 1 {
+		t.Fatal("expected user and tag to be committed")
+	}
+
+	_, err = crudy.Save(&User{Name: "rollback"})
+	if err == nil {
+		t.Fatal("expected error")
+	} else if countOf(&User{}) != 1 || countOf(&Tag{}) != 1 {
+		t.Fatal("expected user and tag to be rolled back")
+	}
+
+	_, err = crudy.Save(&User{Name: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = crudy.Delete(2)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	var user User
+	err = db.First(&user, 2).Error
+	if err != nil {
+		t.Fatal(err)
+	} else if user.DeletedAt != nil {
+		t.Fatal("expected soft delete to be rolled back")
+	} else if countOf(&Tag{}) != 2 {
+		t.Fatal("expected tag deletion to be rolled back")
+	}
+
+	ok, err := crudy.Delete(1)
+	if err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected deleted")
+	}
+}
+
+// agePolicy
+// users of age 100 and above are invisible, users named `denied` can not be saved,
+// reading is denied with `?secret`, deleting is denied with id 3
+type agePolicy struct{}
+
+func (agePolicy) CanRead(context *gin.Context, _ *gorm.DB) error {
+	if context.Query("secret") != "" {
+		return errors.New("no secret")
+	}
+	return nil
+}
+
+func (agePolicy) CanWrite(record *User, _ *gin.Context, _ *gorm.DB) error {
+	if record.Name == "denied" {
+		return errors.New("denied")
+	}
+	return nil
+}
+
+func (agePolicy) CanDelete(context *gin.Context, _ *gorm.DB) error {
+	if context.Param("id") == "3" {
+		return errors.New("undeletable")
+	}
+	return nil
+}
+
+func (agePolicy) Scope(db *gorm.DB, _ *gin.Context) *gorm.DB {
+	return db.Where("age < ?", 100)
+}
+
+func TestPolicy(t *testing.T) {
+	db, engine, err := basicSetup("TestPolicy.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		EnableGetAll:     true,
+		EnablePagination: true,
+		Policy:           agePolicy{},
+		SearchHandlers:   BaseSearchHandlers(nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(6)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Create(&[]User{
+		{Name: "visible", Age: 10},
+		{Name: "invisible", Age: 100},
+		{Name: "undeletable", Age: 20},
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isForbidden := func(err error) bool {
+		var responseError *ResponseError
+		return errors.As(err, &responseError) && responseError.Code == RestCoder.Forbidden()
+	}
+
+	users, err := crudy.All(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(users))
+	}
+
+	count, err := crudy.Count(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 2 {
+		t.Fatalf("expected 2 users, got %d", count)
+	}
+
+	pagination, err := crudy.Pagination(1, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if pagination.Total != 2 || len(pagination.List) != 2 {
+		t.Fatalf("expected 2 users, got %d", pagination.Total)
+	}
+
+	_, err = crudy.Count(SearchParams{"secret": "1"})
+	if !isForbidden(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+
+	_, err = crudy.One(2)
+	if err == nil {
+		t.Fatal("expected out of scope user to be not found")
+	}
+
+	_, err = crudy.Save(&User{Base: Base{ID: 2}, Name: "hijacked"})
+	if !isForbidden(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+
+	_, err = crudy.Save(&User{Name: "denied"})
+	if !isForbidden(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+
+	_, err = crudy.Delete(2)
+	if !isForbidden(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+
+	_, err = crudy.Delete(3)
+	if !isForbidden(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+
+	var user User
+	err = db.First(&user, 2).Error
+	if err != nil {
+		t.Fatal(err)
+	} else if user.Name != "invisible" || user.DeletedAt != nil {
+		t.Fatal("expected out of scope user to be untouched")
+	}
+
+	ok, err := crudy.Delete(1)
+	if err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected deleted")
+	}
+
+	ok, err = crudy.Restore(1)
+	if err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected restored")
+	}
+}
+
+//goland:noinspection GoUnusedFunction
+func testRunCrudServer(t *testing.T) {
+	db, engine, err := basicSetup("TestStartServer.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		EnableGetAll: true,
+		SearchHandlers: BaseSearchHandlers(SearchHandlers{
+			"like_name": KeywordLike("name", nil),
+			"name":      KeywordEqual("name", nil),
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/tag"), db, nil, &Crud[Tag]{
+		EnableGetAll: true,
+		SearchHandlers: BaseSearchHandlers(SearchHandlers{
+			"like_name": KeywordLike("name", nil),
+			"name":      KeywordEqual("name", nil),
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetupM2MConnectorController[UserTag](
+		engine.Group("/user-tag"), db, gogger.New("controller:user-tag"),
+		"UserID", "TagID",
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	static := engine.Group("/static")
+	err = NewHttpFileSystemController(static, TestDataDir, &HttpFileSystemConfig{
+		AllowUpload: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		_ = engine.Run(address.crud.NewAddress(0))
+	}()
+
+	Wait4CtrlC()
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db, engine, err := basicSetup("TestQueryTimeout.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/timeout"), db, nil, &Crud[User]{
+		EnableGetAll: true,
+		QueryTimeout: 100 * time.Millisecond,
+		WillGetAll: func(context *gin.Context, db *gorm.DB) *gorm.DB {
+			time.Sleep(200 * time.Millisecond)
+			return db
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canceled := make(chan error, 1)
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		WillCount: func(context *gin.Context, db *gorm.DB) *gorm.DB {
+			time.Sleep(200 * time.Millisecond)
+			canceled <- db.Statement.Context.Err()
+			return db
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(7)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	timeoutCrudy, err := NewCrudy[User](addr + "/timeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = timeoutCrudy.All(nil)
+	var responseError *ResponseError
+	if !errors.As(err, &responseError) || responseError.Code != RestCoder.InternalServerError() {
+		t.Fatalf("expected query to time out, got %v", err)
+	}
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = crudy.CountContext(ctx, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	if err := <-canceled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected database context to be canceled, got %v", err)
+	}
+
+	handler, err := NewM2MConnectorHandler[User, Tag, UserTag](addr+"/user-tag", nil, nil, "UserID", "TagID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canceledCtx, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+
+	_, err = handler.GetAllContext(canceledCtx, []ID{1}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+}
+
+// statusTransport
+// records status codes of responses
+type statusTransport struct {
+	statuses *[]int
+}
+
+func (s statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil {
+		*s.statuses = append(*s.statuses, resp.StatusCode)
+	}
+	return resp, err
+}
+
+func TestETag(t *testing.T) {
+	db, engine, err := basicSetup("TestETag.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		EnableGetAll:     true,
+		EnableETag:       true,
+		SelectableFields: []string{"name"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(8)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := crudy.Save(&User{Name: "etag", Age: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, url string, headers map[string]string) *http.Response {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	resp := request(http.MethodGet, fmt.Sprintf("%s/user/one/%d", addr, user.ID), nil)
+	etag := resp.Header.Get(HeaderKeyETag)
+	if !strings.HasPrefix(etag, fmt.Sprintf(`"%d-`, user.ID)) {
+		t.Fatalf("unexpected etag %s", etag)
+	}
+
+	resp = request(http.MethodGet, fmt.Sprintf("%s/user/one/%d", addr, user.ID), map[string]string{HeaderKeyIfNoneMatch: etag})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", resp.StatusCode)
+	}
+
+	// another representation with `fields`
+	resp = request(http.MethodGet, fmt.Sprintf("%s/user/one/%d?fields=name", addr, user.ID), map[string]string{HeaderKeyIfNoneMatch: etag})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 of selected fields, got %d", resp.StatusCode)
+	} else if fieldsETag := resp.Header.Get(HeaderKeyETag); fieldsETag == "" || fieldsETag == etag {
+		t.Fatalf("expected another etag of selected fields, got %s", fieldsETag)
+	}
+
+	resp = request(http.MethodGet, addr+"/user/all", nil)
+	listETag := resp.Header.Get(HeaderKeyETag)
+	if listETag == "" {
+		t.Fatal("expected etag of list")
+	}
+
+	resp = request(http.MethodGet, addr+"/user/all", map[string]string{HeaderKeyIfNoneMatch: listETag})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", resp.StatusCode)
+	}
+
+	// If-Match
+	content, err := json.Marshal(User{Base: Base{ID: user.ID}, Name: "stale"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := fetchJSON[any](http.MethodPut, addr+"/user", bytes.NewReader(content), map[string]string{
+		"Content-Type":   "application/json",
+		HeaderKeyIfMatch: `W/"stale"`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Code != RestCoder.PreconditionFailed() {
+		t.Fatalf("expected precondition failed, got %s: %s", res.Code, res.Message)
+	}
+
+	// If-Match compares strongly, a weak one of the same tag does not match
+	res, err = fetchJSON[any](http.MethodPatch, fmt.Sprintf("%s/user/%d", addr, user.ID), strings.NewReader(`{"age":2}`), map[string]string{
+		"Content-Type":   "application/json",
+		HeaderKeyIfMatch: "W/" + etag,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Code != RestCoder.PreconditionFailed() {
+		t.Fatalf("expected precondition failed of weak etag, got %s: %s", res.Code, res.Message)
+	}
+
+	res, err = fetchJSON[any](http.MethodPatch, fmt.Sprintf("%s/user/%d", addr, user.ID), strings.NewReader(`{"age":2}`), map[string]string{
+		"Content-Type":   "application/json",
+		HeaderKeyIfMatch: etag,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Code != RestCoder.OK() {
+		t.Fatalf("expected patch to succeed, got %s: %s", res.Code, res.Message)
+	}
+
+	res, err = fetchJSON[any](http.MethodDelete, fmt.Sprintf("%s/user/%d", addr, user.ID), nil, map[string]string{
+		HeaderKeyIfMatch: etag,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Code != RestCoder.PreconditionFailed() {
+		t.Fatalf("expected precondition failed with the etag before patch, got %s: %s", res.Code, res.Message)
+	}
+
+	// cache of Crudy
+	var statuses []int
+	cachedCrudy, err := NewCrudy[User](
+		addr+"/user",
+		CrudyBasicOptions[User]{HttpClient: &http.Client{Transport: statusTransport{statuses: &statuses}}},
+		CrudyCacheOptions[User]{Enable: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		cached, err := cachedCrudy.One(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		} else if cached.Age != 2 {
+			t.Fatalf("expected age 2, got %d", cached.Age)
+		}
+	}
+	if !slices.Equal(statuses, []int{http.StatusOK, http.StatusNotModified}) {
+		t.Fatalf("expected the second one to be revalidated, got %v", statuses)
+	}
+
+	_, err = crudy.Patch(user.ID, map[string]any{"age": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cached, err := cachedCrudy.One(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if cached.Age != 3 {
+		t.Fatalf("expected age 3 after revalidation, got %d", cached.Age)
+	}
+}
+
+// subscribeEvents
+// events of url until ctx is done, the channel is closed once the stream ends
+func subscribeEvents[T any](t *testing.T, ctx context.Context, url string) <-chan Event[T] {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Header.Get("Content-Type") != eventStreamContentType {
+		t.Fatalf("unexpected content type %s", resp.Header.Get("Content-Type"))
+	}
+
+	events := make(chan Event[T], EventBufferSize)
+	go func() {
+		defer func() {
+			_ = resp.Body.Close()
+			close(events)
+		}()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var event Event[T]
+			if json.Unmarshal([]byte(data), &event) == nil {
+				events <- event
+			}
+		}
+	}()
+	return events
+}
+
+func nextEvent[T any](t *testing.T, events <-chan Event[T]) Event[T] {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event received")
+		return Event[T]{}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	db, engine, err := basicSetup("TestEvents.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		EnableEvents:     true,
+		EventsWithRecord: true,
+		SearchHandlers: SearchHandlers{
+			"name": KeywordEqual("name", nil),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudExtra.NewAddress(9)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	all := subscribeEvents[User](t, ctx, addr+"/user/events")
+	bobs := subscribeEvents[User](t, ctx, addr+"/user/events?name=bob")
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice, err := crudy.Save(&User{Name: "alice", Age: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := crudy.Save(&User{Name: "bob", Age: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob.Age = 3
+	bob, err = crudy.Save(bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = crudy.Delete(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = crudy.Delete(bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		eventType EventType
+		id        ID
+	}{
+		{EventTypeCreated, alice.ID},
+		{EventTypeCreated, bob.ID},
+		{EventTypeUpdated, bob.ID},
+		{EventTypeDeleted, alice.ID},
+		{EventTypeDeleted, bob.ID},
+	}
+	for _, e := range expected {
+		event := nextEvent(t, all)
+		if event.Type != e.eventType || !slices.Equal(event.IDs, Keys{e.id}) {
+			t.Fatalf("expected %s of %d, got %s of %v", e.eventType, e.id, event.Type, event.IDs)
+		}
+	}
+
+	// events of alice are filtered out
+	for _, e := range expected {
+		if e.id != bob.ID {
+			continue
+		}
+		event := nextEvent(t, bobs)
+		if event.Type != e.eventType || !slices.Equal(event.IDs, Keys{e.id}) {
+			t.Fatalf("expected %s of %d, got %s of %v", e.eventType, e.id, event.Type, event.IDs)
+		}
+		if e.eventType == EventTypeUpdated && (event.Record == nil || event.Record.Age != 3) {
+			t.Fatalf("expected record with age 3, got %v", event.Record)
+		}
+	}
+}
+
+func TestEventsOfHardDeletesAndImports(t *testing.T) {
+	db, engine, err := basicSetup("TestEventsOfHardDeletesAndImports.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := &Crud[User]{
+		EnableEvents: true,
+		EnableImport: true,
+		OnDelete:     NewHardDeleteHandler[User](RestCoder),
+		SearchHandlers: SearchHandlers{
+			"name": KeywordEqual("name", nil),
+		},
+	}
+	err = Setup(engine.Group("/user"), db, nil, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.events.NewAddress(0)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bobs := subscribeEvents[User](t, ctx, addr+"/user/events?name=bob")
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := crudy.Import(ExportFormatNDJSON, strings.NewReader("{\"name\":\"alice\"}\n{\"name\":\"bob\"}"), false)
+	if err != nil {
+		t.Fatal(err)
+	} else if report.Imported != 2 {
+		t.Fatalf("expected 2 imported, got %d", report.Imported)
+	}
+
+	event := nextEvent(t, bobs)
+	if event.Type != EventTypeCreated || len(event.IDs) != 1 {
+		t.Fatalf("expected created of bob, got %s of %v", event.Type, event.IDs)
+	}
+	bob := event.IDs[0]
+
+	// a record which does not exist is not deleted, and publishes nothing
+	if deleted, err := crudy.Delete(bob.(ID) + 100); err != nil {
+		t.Fatal(err)
+	} else if deleted {
+		t.Fatal("expected nothing deleted")
+	}
+
+	if _, err = crudy.Delete(bob.(ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	event = nextEvent(t, bobs)
+	if event.Type != EventTypeDeleted || !slices.Equal(event.IDs, Keys{bob}) {
+		t.Fatalf("expected deleted of %v, got %s of %v", bob, event.Type, event.IDs)
+	}
+
+	var count int64
+	if err := db.Model(&User{}).Unscoped().Where("name = ?", "bob").Count(&count).Error; err != nil {
+		t.Fatal(err)
+	} else if count != 0 {
+		t.Fatalf("expected bob hard deleted, got %d", count)
+	}
+
+	users.CloseEvents()
+
+	select {
+	case event, ok := <-bobs:
+		if ok {
+			t.Fatalf("expected no event after closed, got %s of %v", event.Type, event.IDs)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected the stream to end after closed")
+	}
 }
@@ -0,0 +1,47 @@
+package gocrud
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validate
+// validates record with Validator, then Validators
+func (crud *Crud[T]) validate(context *gin.Context, record *T) (Code, error) {
+	err := crud.Validator.Validate(record)
+	if err != nil {
+		var fieldErrors FieldErrors
+		if errors.As(err, &fieldErrors) {
+			return crud.Coder.BadRequest(), fieldErrors
+		}
+		crud.logger.Error().Printf("save: failed to validate record: %v", err)
+		return crud.Coder.InternalServerError(), errors.New("[error] validate failed")
+	}
+
+	var fieldErrors FieldErrors
+	for _, validate := range crud.Validators {
+		fieldErrors = append(fieldErrors, validate(record, context)...)
+	}
+	if len(fieldErrors) > 0 {
+		return crud.Coder.BadRequest(), fieldErrors
+	}
+
+	return "", nil
+}
+
+// saveError
+// responds FieldErrors as data, unless the context has been aborted
+func (crud *Crud[T]) saveError(context *gin.Context, code Code, err error) {
+	if context.IsAborted() {
+		return
+	}
+
+	var fieldErrors FieldErrors
+	if errors.As(err, &fieldErrors) {
+		crud.errorWithData(context, code, fieldErrors)
+		return
+	}
+
+	crud.error(context, code, err)
+}
@@ -0,0 +1,49 @@
+package gocrud
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// enqueueWebhook
+// db should be the transaction of the change, does nothing if Webhook is nil.
+// record should be censored, see decensorWebhookRecord.
+func (crud *Crud[T]) enqueueWebhook(db *gorm.DB, eventType EventType, ids []Key, record *T) error {
+	if crud.Webhook == nil || len(ids) == 0 {
+		return nil
+	}
+	return crud.Webhook.Enqueue(db, crud.auditModel(), eventType, ids, record)
+}
+
+// notifyWebhook
+// wakes up the worker of Webhook once the change is committed
+func (crud *Crud[T]) notifyWebhook() {
+	if crud.Webhook != nil {
+		crud.Webhook.Notify()
+	}
+}
+
+// decensorWebhookRecord
+// decensors a queued record with GetWebhookCensors when it is sent
+func (crud *Crud[T]) decensorWebhookRecord(ctx context.Context, content json.RawMessage) (json.RawMessage, error) {
+	record := new(T)
+	err := json.Unmarshal(content, record)
+	if err != nil {
+		return nil, err
+	}
+
+	censors, err := crud.GetWebhookCensors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, censor := range censors {
+		err = censor.Decensor(record)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(record)
+}
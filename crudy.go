@@ -2,13 +2,21 @@ package gocrud
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"maps"
 	"net/http"
 	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
 )
 
 type (
@@ -21,6 +29,16 @@ var (
 	ErrorBaseURLRequired       = errors.New("BaseURL is required")
 )
 
+// ConflictError
+// returned by Crudy when the record has been modified by others, see Crud.OptimisticLock
+type ConflictError struct {
+	ResponseError
+}
+
+func (e *ConflictError) Unwrap() error {
+	return &e.ResponseError
+}
+
 type CrudyOption[T any] interface {
 	Apply(*Crudy[T]) error
 }
@@ -30,6 +48,7 @@ type CrudyBasicOptions[T any] struct {
 	BaseURL             string
 	HttpClient          *http.Client
 	OkayHttpStatusRange *HttpStatusRange
+	Coder               Coder
 }
 
 func (b CrudyBasicOptions[T]) Apply(crudy *Crudy[T]) error {
@@ -42,6 +61,34 @@ func (b CrudyBasicOptions[T]) Apply(crudy *Crudy[T]) error {
 	if b.OkayHttpStatusRange != nil {
 		crudy.okayHttpStatusRange = b.OkayHttpStatusRange
 	}
+	if b.Coder != nil {
+		crudy.coder = b.Coder
+	}
+	return nil
+}
+
+// DefaultCrudyCacheCapacity
+// capacity of the LRUCache of CrudyCacheOptions if Cache is nil
+const DefaultCrudyCacheCapacity = 1024
+
+// CrudyCacheOptions
+// caches responses of reads those have an ETag, and revalidates them with If-None-Match, see Crud.EnableETag
+type CrudyCacheOptions[T any] struct {
+	CrudyOption[T]
+	Enable bool
+	// Cache
+	// where responses are stored, an LRUCache of DefaultCrudyCacheCapacity will be used if it is nil
+	Cache Cache
+}
+
+func (b CrudyCacheOptions[T]) Apply(crudy *Crudy[T]) error {
+	if !b.Enable {
+		return nil
+	}
+	crudy.cache = b.Cache
+	if crudy.cache == nil {
+		crudy.cache = NewLRUCache(DefaultCrudyCacheCapacity, 0)
+	}
 	return nil
 }
 
@@ -55,11 +102,29 @@ func (b CrudyPageOptions[T]) Apply(crudy *Crudy[T]) error {
 	return nil
 }
 
+// CrudyKeyOptions
+// database columns of the composite key of T in order, which are parsed with the default naming strategy of gorm if not specified
+type CrudyKeyOptions[T any] struct {
+	CrudyOption[T]
+	Columns []string
+}
+
+func (b CrudyKeyOptions[T]) Apply(crudy *Crudy[T]) error {
+	crudy.keyColumns = b.Columns
+	return nil
+}
+
 func NewCrudy[T any](baseURL string, options ...CrudyOption[T]) (*Crudy[T], error) {
 	crudy := &Crudy[T]{
 		baseURL: baseURL,
 	}
 
+	if s, err := schema.Parse(new(T), &sync.Map{}, schema.NamingStrategy{}); err == nil && len(s.PrimaryFields) > 1 {
+		for _, field := range s.PrimaryFields {
+			crudy.keyColumns = append(crudy.keyColumns, field.DBName)
+		}
+	}
+
 	for _, option := range options {
 		err := option.Apply(crudy)
 		if err != nil {
@@ -82,6 +147,9 @@ func NewCrudy[T any](baseURL string, options ...CrudyOption[T]) (*Crudy[T], erro
 	if crudy.okayHttpStatusRange == nil {
 		crudy.okayHttpStatusRange = &DefaultOkayHttpStatusRange
 	}
+	if crudy.coder == nil {
+		crudy.coder = RestCoder
+	}
 
 	if crudy.defaultPageSize == 0 {
 		crudy.defaultPageSize = DefaultPageSize
@@ -90,18 +158,72 @@ func NewCrudy[T any](baseURL string, options ...CrudyOption[T]) (*Crudy[T], erro
 	return crudy, nil
 }
 
+// responseErrorOf
+// returns the structured error of res, or builds one from its code and message for legacy servers
+func responseErrorOf[T any](res *R[T]) *ResponseError {
+	if res.Error != nil {
+		return res.Error
+	}
+	return &ResponseError{Code: res.Code, Message: res.Message}
+}
+
 func MakeJSONRequest[T any](
 	httpClient *http.Client, okayHttpStatusRange *HttpStatusRange,
 	u *url.URL, method string,
 	body io.Reader, res *R[T],
 ) error {
-	req, err := http.NewRequest(method, u.String(), body)
+	return MakeJSONRequestContext(context.Background(), httpClient, okayHttpStatusRange, u, method, body, res)
+}
+
+// MakeJSONRequestContext
+// same as MakeJSONRequest, the request is canceled with ctx
+func MakeJSONRequestContext[T any](
+	ctx context.Context,
+	httpClient *http.Client, okayHttpStatusRange *HttpStatusRange,
+	u *url.URL, method string,
+	body io.Reader, res *R[T],
+) error {
+	return makeJSONRequest(ctx, nil, httpClient, okayHttpStatusRange, u, method, body, res)
+}
+
+// makeJSONRequest
+// revalidates the cached response of the same request with If-None-Match if cache is not nil
+func makeJSONRequest[T any](
+	ctx context.Context, cache Cache,
+	httpClient *http.Client, okayHttpStatusRange *HttpStatusRange,
+	u *url.URL, method string,
+	body io.Reader, res *R[T],
+) error {
+	var key string
+	var cached *etagCacheEntry
+
+	if cache != nil {
+		var payload []byte
+		if body != nil {
+			var err error
+			payload, err = io.ReadAll(body)
+			if err != nil {
+				return err
+			}
+			body = bytes.NewReader(payload)
+		}
+
+		key = method + " " + u.String() + "\n" + string(payload)
+		if value, ok := cache.Get(key); ok {
+			cached, _ = value.(*etagCacheEntry)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	req.Header.Set("Accept", "application/json")
+	if cached != nil {
+		req.Header.Set(HeaderKeyIfNoneMatch, cached.etag)
+	}
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
@@ -111,15 +233,27 @@ func MakeJSONRequest[T any](
 		_ = resp.Body.Close()
 	}()
 
-	if okayHttpStatusRange != nil {
-		if resp.StatusCode < okayHttpStatusRange[0] || resp.StatusCode >= okayHttpStatusRange[1] {
-			return fmt.Errorf("status code: %d", resp.StatusCode)
+	var content []byte
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		content = cached.content
+	} else {
+		if okayHttpStatusRange != nil {
+			if resp.StatusCode < okayHttpStatusRange[0] || resp.StatusCode >= okayHttpStatusRange[1] {
+				return fmt.Errorf("status code: %d", resp.StatusCode)
+			}
 		}
-	}
 
-	content, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+		content, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		if cache != nil {
+			if etag := resp.Header.Get(HeaderKeyETag); etag != "" {
+				cache.Set(key, &etagCacheEntry{etag: etag, content: content})
+			}
+		}
 	}
 
 	err = json.Unmarshal(content, res)
@@ -131,7 +265,7 @@ func MakeJSONRequest[T any](
 		}
 
 		if anyRes.Code != "0" {
-			return errors.New(anyRes.Message)
+			return responseErrorOf(&anyRes)
 		}
 
 		reflected := reflect.TypeFor[T]()
@@ -139,7 +273,7 @@ func MakeJSONRequest[T any](
 	}
 
 	if res.Code != "0" {
-		return errors.New(res.Message)
+		return responseErrorOf(res)
 	}
 
 	return nil
@@ -151,6 +285,74 @@ type Crudy[T any] struct {
 	okayHttpStatusRange *HttpStatusRange // okayHttpStatusRange[0] <= status code < okayHttpStatusRange[1]
 
 	defaultPageSize uint64
+
+	coder Coder
+
+	cache Cache
+
+	// keyColumns
+	// columns of the composite key of T, empty for a single primary field
+	keyColumns []string
+}
+
+// asConflictError
+// converts err into a ConflictError if its code is Coder.Conflict()
+func (c *Crudy[T]) asConflictError(err error) error {
+	var responseError *ResponseError
+	if errors.As(err, &responseError) && responseError.Code == c.coder.Conflict() {
+		return &ConflictError{ResponseError: *responseError}
+	}
+	return err
+}
+
+// keyPath
+// `/1,2`, or `/1,1/a,b` of CompositeKey with values in order of keyColumns,
+// keys containing `,` are rejected, since the server splits them by it even if it is escaped
+func (c *Crudy[T]) keyPath(ids ...Key) (string, error) {
+	format := func(key Key) (string, error) {
+		formatted := FormatKey(key)
+		if strings.Contains(formatted, ",") {
+			return "", fmt.Errorf("%w: %s contains ,", InvalidKeyError, formatted)
+		}
+		return formatted, nil
+	}
+
+	if len(c.keyColumns) == 0 {
+		values := make([]string, len(ids))
+		for i, id := range ids {
+			value, err := format(id)
+			if err != nil {
+				return "", err
+			}
+			values[i] = value
+		}
+		return "/" + url.PathEscape(strings.Join(values, ",")), nil
+	}
+
+	values := make([][]string, len(c.keyColumns))
+	for _, id := range ids {
+		composite, ok := id.(CompositeKey)
+		if !ok {
+			return "", fmt.Errorf("%w: %v is not a composite key", InvalidKeyError, id)
+		}
+		for i, column := range c.keyColumns {
+			key, ok := composite[column]
+			if !ok {
+				return "", fmt.Errorf("%w: %s is missing in %s", InvalidKeyError, column, composite)
+			}
+			value, err := format(key)
+			if err != nil {
+				return "", err
+			}
+			values[i] = append(values[i], value)
+		}
+	}
+
+	var path strings.Builder
+	for _, value := range values {
+		path.WriteString("/" + url.PathEscape(strings.Join(value, ",")))
+	}
+	return path.String(), nil
 }
 
 func (c *Crudy[T]) BuildURL(uri string, searchParams SearchParams) (*url.URL, error) {
@@ -169,6 +371,12 @@ func (c *Crudy[T]) BuildURL(uri string, searchParams SearchParams) (*url.URL, er
 }
 
 func (c *Crudy[T]) Page(current, size uint64, searchParams SearchParams) ([]T, error) {
+	return c.PageContext(context.Background(), current, size, searchParams)
+}
+
+// PageContext
+// same as Page, the request is canceled with ctx
+func (c *Crudy[T]) PageContext(ctx context.Context, current, size uint64, searchParams SearchParams) ([]T, error) {
 	if current <= 0 {
 		current = 1
 	}
@@ -187,7 +395,7 @@ func (c *Crudy[T]) Page(current, size uint64, searchParams SearchParams) ([]T, e
 	}
 
 	var res R[[]T]
-	err = MakeJSONRequest(c.httpClient, c.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), &res)
+	err = makeJSONRequest(ctx, c.cache, c.httpClient, c.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), &res)
 	if err != nil {
 		return nil, err
 	}
@@ -195,7 +403,122 @@ func (c *Crudy[T]) Page(current, size uint64, searchParams SearchParams) ([]T, e
 	return res.Data, nil
 }
 
+// Pagination
+// same as Page, with the total count and page metadata, see Crud.EnablePagination.
+// Page still returns the plain list of `/page`, which is served by every Crud.
+func (c *Crudy[T]) Pagination(current, size uint64, searchParams SearchParams) (*Pagination[T], error) {
+	return c.PaginationContext(context.Background(), current, size, searchParams)
+}
+
+// PaginationContext
+// same as Pagination, the request is canceled with ctx
+func (c *Crudy[T]) PaginationContext(ctx context.Context, current, size uint64, searchParams SearchParams) (*Pagination[T], error) {
+	if current <= 0 {
+		current = 1
+	}
+	if size <= 0 {
+		size = c.defaultPageSize
+	}
+
+	u, err := c.BuildURL(fmt.Sprintf("/pagination/%d/%d", current, size), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(searchParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var res R[Pagination[T]]
+	err = makeJSONRequest(ctx, c.cache, c.httpClient, c.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+// Cursor
+// cursor: Next of the previous CursorPage, empty for the first page
+func (c *Crudy[T]) Cursor(cursor string, size uint64, searchParams SearchParams) (*CursorPage[T], error) {
+	return c.CursorContext(context.Background(), cursor, size, searchParams)
+}
+
+// CursorContext
+// same as Cursor, the request is canceled with ctx
+func (c *Crudy[T]) CursorContext(ctx context.Context, cursor string, size uint64, searchParams SearchParams) (*CursorPage[T], error) {
+	if size <= 0 {
+		size = c.defaultPageSize
+	}
+
+	u, err := c.BuildURL(fmt.Sprintf("/cursor/%d", size), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	params := maps.Clone(searchParams)
+	if params == nil {
+		params = make(SearchParams)
+	}
+	if cursor != "" {
+		params[SearchKeyCursor] = cursor
+	}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var res R[CursorPage[T]]
+	err = makeJSONRequest(ctx, c.cache, c.httpClient, c.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+// Iterate
+// follows the cursors page by page until there is no more records, or an error occurs
+func (c *Crudy[T]) Iterate(size uint64, searchParams SearchParams) iter.Seq2[T, error] {
+	return c.IterateContext(context.Background(), size, searchParams)
+}
+
+// IterateContext
+// same as Iterate, the requests are canceled with ctx
+func (c *Crudy[T]) IterateContext(ctx context.Context, size uint64, searchParams SearchParams) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		cursor := ""
+		for {
+			page, err := c.CursorContext(ctx, cursor, size, searchParams)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, record := range page.List {
+				if !yield(record, nil) {
+					return
+				}
+			}
+
+			if page.Next == "" {
+				return
+			}
+			cursor = page.Next
+		}
+	}
+}
+
 func (c *Crudy[T]) All(searchParams SearchParams) ([]T, error) {
+	return c.AllContext(context.Background(), searchParams)
+}
+
+// AllContext
+// same as All, the request is canceled with ctx
+func (c *Crudy[T]) AllContext(ctx context.Context, searchParams SearchParams) ([]T, error) {
 	u, err := c.BuildURL("/all", nil)
 	if err != nil {
 		return nil, err
@@ -207,7 +530,7 @@ func (c *Crudy[T]) All(searchParams SearchParams) ([]T, error) {
 	}
 
 	var res R[[]T]
-	err = MakeJSONRequest(c.httpClient, c.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), &res)
+	err = makeJSONRequest(ctx, c.cache, c.httpClient, c.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), &res)
 	if err != nil {
 		return nil, err
 	}
@@ -215,14 +538,100 @@ func (c *Crudy[T]) All(searchParams SearchParams) ([]T, error) {
 	return res.Data, nil
 }
 
+// Export
+// writes exported records into writer, see Crud.EnableExport
+func (c *Crudy[T]) Export(format ExportFormat, searchParams SearchParams, writer io.Writer) error {
+	return c.ExportContext(context.Background(), format, searchParams, writer)
+}
+
+// ExportContext
+// same as Export, the request is canceled with ctx
+func (c *Crudy[T]) ExportContext(ctx context.Context, format ExportFormat, searchParams SearchParams, writer io.Writer) error {
+	u, err := c.BuildURL(fmt.Sprintf("/export/%s", format), nil)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(searchParams)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		content, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		var res R[any]
+		err = json.Unmarshal(content, &res)
+		if err != nil {
+			return err
+		}
+		return responseErrorOf(&res)
+	}
+
+	if resp.StatusCode < c.okayHttpStatusRange[0] || resp.StatusCode >= c.okayHttpStatusRange[1] {
+		return fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+
+	_, err = io.Copy(writer, resp.Body)
+	return err
+}
+
+// Import
+// reader: content in format, see Crud.EnableImport
+func (c *Crudy[T]) Import(format ExportFormat, reader io.Reader, dryRun bool) (*ImportReport, error) {
+	return c.ImportContext(context.Background(), format, reader, dryRun)
+}
+
+// ImportContext
+// same as Import, the request is canceled with ctx
+func (c *Crudy[T]) ImportContext(ctx context.Context, format ExportFormat, reader io.Reader, dryRun bool) (*ImportReport, error) {
+	u, err := c.BuildURL(fmt.Sprintf("/import/%s", format), SearchParams{
+		QueryKeyDryRun: strconv.FormatBool(dryRun),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res R[ImportReport]
+	err = MakeJSONRequestContext(ctx, c.httpClient, c.okayHttpStatusRange, u, http.MethodPost, reader, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
 func (c *Crudy[T]) Count(searchParams SearchParams) (uint64, error) {
+	return c.CountContext(context.Background(), searchParams)
+}
+
+// CountContext
+// same as Count, the request is canceled with ctx
+func (c *Crudy[T]) CountContext(ctx context.Context, searchParams SearchParams) (uint64, error) {
 	u, err := c.BuildURL("/count", searchParams)
 	if err != nil {
 		return 0, err
 	}
 
 	var res R[uint64]
-	err = MakeJSONRequest(c.httpClient, c.okayHttpStatusRange, u, http.MethodGet, nil, &res)
+	err = makeJSONRequest(ctx, c.cache, c.httpClient, c.okayHttpStatusRange, u, http.MethodGet, nil, &res)
 	if err != nil {
 		return 0, err
 	}
@@ -230,14 +639,27 @@ func (c *Crudy[T]) Count(searchParams SearchParams) (uint64, error) {
 	return res.Data, nil
 }
 
-func (c *Crudy[T]) One(id ID) (*T, error) {
-	u, err := c.BuildURL(fmt.Sprintf("/one/%d", id), nil)
+// One
+// id: ID, or any key formatted by FormatKey, such as a UUID, or a CompositeKey such as `CompositeKey{"org_id": 1, "code": "a"}`
+func (c *Crudy[T]) One(id Key) (*T, error) {
+	return c.OneContext(context.Background(), id)
+}
+
+// OneContext
+// same as One, the request is canceled with ctx
+func (c *Crudy[T]) OneContext(ctx context.Context, id Key) (*T, error) {
+	path, err := c.keyPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.BuildURL("/one"+path, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var res R[T]
-	err = MakeJSONRequest(c.httpClient, c.okayHttpStatusRange, u, http.MethodGet, nil, &res)
+	err = makeJSONRequest(ctx, c.cache, c.httpClient, c.okayHttpStatusRange, u, http.MethodGet, nil, &res)
 	if err != nil {
 		return nil, err
 	}
@@ -246,6 +668,12 @@ func (c *Crudy[T]) One(id ID) (*T, error) {
 }
 
 func (c *Crudy[T]) Save(t *T) (*T, error) {
+	return c.SaveContext(context.Background(), t)
+}
+
+// SaveContext
+// same as Save, the request is canceled with ctx
+func (c *Crudy[T]) SaveContext(ctx context.Context, t *T) (*T, error) {
 	u, err := c.BuildURL("", nil)
 	if err != nil {
 		return nil, err
@@ -257,22 +685,144 @@ func (c *Crudy[T]) Save(t *T) (*T, error) {
 	}
 
 	var res R[T]
-	err = MakeJSONRequest(c.httpClient, c.okayHttpStatusRange, u, http.MethodPut, bytes.NewReader(content), &res)
+	err = MakeJSONRequestContext(ctx, c.httpClient, c.okayHttpStatusRange, u, http.MethodPut, bytes.NewReader(content), &res)
+	if err != nil {
+		return nil, c.asConflictError(err)
+	}
+
+	return &res.Data, nil
+}
+
+// Patch
+// fields: json field name to value, only these fields will be updated
+func (c *Crudy[T]) Patch(id Key, fields map[string]any) (*T, error) {
+	return c.PatchContext(context.Background(), id, fields)
+}
+
+// PatchContext
+// same as Patch, the request is canceled with ctx
+func (c *Crudy[T]) PatchContext(ctx context.Context, id Key, fields map[string]any) (*T, error) {
+	path, err := c.keyPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.BuildURL(path, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	content, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var res R[T]
+	err = MakeJSONRequestContext(ctx, c.httpClient, c.okayHttpStatusRange, u, http.MethodPatch, bytes.NewReader(content), &res)
+	if err != nil {
+		return nil, c.asConflictError(err)
+	}
+
 	return &res.Data, nil
 }
 
-func (c *Crudy[T]) Delete(id ID) (bool, error) {
-	u, err := c.BuildURL(fmt.Sprintf("/%d", id), nil)
+func (c *Crudy[T]) BatchSave(records []T) ([]T, error) {
+	return c.BatchSaveContext(context.Background(), records)
+}
+
+// BatchSaveContext
+// same as BatchSave, the request is canceled with ctx
+func (c *Crudy[T]) BatchSaveContext(ctx context.Context, records []T) ([]T, error) {
+	u, err := c.BuildURL("/batch", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var res R[[]T]
+	err = MakeJSONRequestContext(ctx, c.httpClient, c.okayHttpStatusRange, u, http.MethodPut, bytes.NewReader(content), &res)
+	if err != nil {
+		return nil, c.asConflictError(err)
+	}
+
+	return res.Data, nil
+}
+
+func (c *Crudy[T]) Delete(id Key) (bool, error) {
+	return c.DeleteContext(context.Background(), id)
+}
+
+// DeleteContext
+// same as Delete, the request is canceled with ctx
+func (c *Crudy[T]) DeleteContext(ctx context.Context, id Key) (bool, error) {
+	path, err := c.keyPath(id)
+	if err != nil {
+		return false, err
+	}
+
+	u, err := c.BuildURL(path, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var res R[bool]
+	err = MakeJSONRequestContext(ctx, c.httpClient, c.okayHttpStatusRange, u, http.MethodDelete, nil, &res)
+	if err != nil {
+		return false, err
+	}
+
+	return res.Data, nil
+}
+
+func (c *Crudy[T]) DeleteMany(ids []Key) (bool, error) {
+	return c.DeleteManyContext(context.Background(), ids)
+}
+
+// DeleteManyContext
+// same as DeleteMany, the request is canceled with ctx
+func (c *Crudy[T]) DeleteManyContext(ctx context.Context, ids []Key) (bool, error) {
+	path, err := c.keyPath(ids...)
+	if err != nil {
+		return false, err
+	}
+
+	u, err := c.BuildURL(path, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var res R[bool]
+	err = MakeJSONRequestContext(ctx, c.httpClient, c.okayHttpStatusRange, u, http.MethodDelete, nil, &res)
+	if err != nil {
+		return false, err
+	}
+
+	return res.Data, nil
+}
+
+func (c *Crudy[T]) Restore(ids ...Key) (bool, error) {
+	return c.RestoreContext(context.Background(), ids...)
+}
+
+// RestoreContext
+// same as Restore, the request is canceled with ctx
+func (c *Crudy[T]) RestoreContext(ctx context.Context, ids ...Key) (bool, error) {
+	path, err := c.keyPath(ids...)
+	if err != nil {
+		return false, err
+	}
+
+	u, err := c.BuildURL("/restore"+path, nil)
 	if err != nil {
 		return false, err
 	}
 
 	var res R[bool]
-	err = MakeJSONRequest(c.httpClient, c.okayHttpStatusRange, u, http.MethodDelete, nil, &res)
+	err = MakeJSONRequestContext(ctx, c.httpClient, c.okayHttpStatusRange, u, http.MethodPost, nil, &res)
 	if err != nil {
 		return false, err
 	}
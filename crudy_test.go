@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -183,3 +184,109 @@ func TestNewCrudy(t *testing.T) {
 		t.Fatalf("got %v, want %v", crudy.okayHttpStatusRange[1], 456)
 	}
 }
+
+func TestResponseError(t *testing.T) {
+	db, engine, err := basicSetup("TestResponseError.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&VersionedTag{}, &ValidatedUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine.Use(RequestIDHandler())
+
+	err = Setup(engine.Group("/versioned-tag"), db, nil, &Crud[VersionedTag]{
+		OptimisticLock: true,
+		VersionField:   "Version",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[ValidatedUser]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.crudy.NewAddress(1)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	tagCrudy, err := NewCrudy[VersionedTag](addr + "/versioned-tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := tagCrudy.Save(&VersionedTag{Name: "tag1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var responseError *ResponseError
+
+	_, err = tagCrudy.Save(&VersionedTag{Base: Base{ID: tag.ID}, Name: "tag1-stale", Version: 100})
+	if !errors.As(err, &responseError) {
+		t.Fatalf("expected ResponseError, got %v", err)
+	} else if responseError.Code != RestCoder.Conflict() {
+		t.Fatalf("expected conflict, got %s", responseError.Code)
+	} else if responseError.RequestID == "" {
+		t.Fatal("expected request id")
+	} else if responseError.Details == nil || len(responseError.Details.IDs) != 1 || responseError.Details.IDs[0] != tag.ID {
+		t.Fatalf("expected conflicting id %d, got %v", tag.ID, responseError.Details)
+	}
+
+	userCrudy, err := NewCrudy[ValidatedUser](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = userCrudy.Save(&ValidatedUser{Age: -1})
+	if !errors.As(err, &responseError) {
+		t.Fatalf("expected ResponseError, got %v", err)
+	} else if responseError.Code != RestCoder.BadRequest() {
+		t.Fatalf("expected bad request, got %s", responseError.Code)
+	} else if responseError.Details == nil || !slices.Equal(fieldsOf(responseError.Details.Fields), []string{"age", "name"}) {
+		t.Fatalf("unexpected details %v", responseError.Details)
+	}
+
+	_, err = userCrudy.BatchSave([]ValidatedUser{{Name: "bob"}, {Name: "carol", Email: "carol"}})
+	if !errors.As(err, &responseError) {
+		t.Fatalf("expected ResponseError, got %v", err)
+	} else if responseError.Details == nil || !slices.Equal(fieldsOf(responseError.Details.Fields), []string{"[1].email"}) {
+		t.Fatalf("unexpected details %v", responseError.Details)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr+"/user/one/0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(HeaderKeyRequestID, "request-1")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var res R[any]
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Error == nil || res.Error.RequestID != "request-1" || res.Error.Code != res.Code {
+		t.Fatalf("unexpected error %v", res.Error)
+	} else if resp.Header.Get(HeaderKeyRequestID) != "request-1" {
+		t.Fatalf("expected request id in header, got %s", resp.Header.Get(HeaderKeyRequestID))
+	}
+}
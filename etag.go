@@ -0,0 +1,92 @@
+package gocrud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	HeaderKeyETag        = "ETag"
+	HeaderKeyIfNoneMatch = "If-None-Match"
+	HeaderKeyIfMatch     = "If-Match"
+)
+
+// StrongETag
+// formats value as a strong entity tag, such as `"1-1700000000000000000"`
+func StrongETag(value string) string {
+	return `"` + value + `"`
+}
+
+// WeakETag
+// formats value as a weak entity tag, such as `W/"1-1700000000000000000"`
+func WeakETag(value string) string {
+	return `W/"` + value + `"`
+}
+
+// HashETagOf
+// returns a weak entity tag of the json of data
+func HashETagOf(data any) (string, error) {
+	hash, err := hashOf(data)
+	if err != nil {
+		return "", err
+	}
+	return WeakETag(hash), nil
+}
+
+func hashOf(data any) (string, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// ETagMatches
+// reports whether header, a comma separated list of entity tags or `*`, matches etag by the weak comparison,
+// which is used for If-None-Match
+func ETagMatches(header string, etag string) bool {
+	return etagMatches(header, etag, false)
+}
+
+// ETagMatchesStrongly
+// reports whether header matches etag by the strong comparison, which is used for If-Match,
+// weak entity tags never match
+func ETagMatchesStrongly(header string, etag string) bool {
+	return etagMatches(header, etag, true)
+}
+
+func etagMatches(header string, etag string, strong bool) bool {
+	header = strings.TrimSpace(header)
+	if header == "" || etag == "" {
+		return false
+	} else if header == "*" {
+		return true
+	}
+
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	opaque := strings.TrimPrefix(etag, "W/")
+	for tag := range strings.SplitSeq(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strong && strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(tag, "W/") == opaque {
+			return true
+		}
+	}
+
+	return false
+}
+
+// etagCacheEntry
+// a response cached by Crudy
+type etagCacheEntry struct {
+	etag    string
+	content []byte
+}
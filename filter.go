@@ -0,0 +1,217 @@
+package gocrud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SearchKeyFilter
+// reserved search key for FilterNode in json, see Crud.FilterableFields
+const SearchKeyFilter = "filter"
+
+// DefaultFilterMaxDepth
+// nodes deeper than this will be rejected
+const DefaultFilterMaxDepth = 8
+
+var (
+	InvalidFilterNodeError = errors.New("filter node should be exactly one of and, or, not, or a field")
+	TooDeepFilterError     = errors.New("filter is too deep")
+)
+
+// FilterError
+// a malformed or disallowed filter of search key `filter`, which is an error of the client
+type FilterError struct {
+	Err error
+}
+
+func (e *FilterError) Error() string {
+	return "invalid filter: " + e.Err.Error()
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// FilterNode
+// a group of And, Or or Not, or a leaf of Field, Op and Value, such as
+// `{"or": [{"field": "status", "op": "=", "value": "A"}, {"not": {"field": "name", "op": "LIKE", "value": "x%"}}]}`.
+// Value should be an array for IN and BETWEEN, and will be ignored for IS NULL.
+type FilterNode struct {
+	And []FilterNode `json:"and,omitempty"`
+	Or  []FilterNode `json:"or,omitempty"`
+	Not *FilterNode  `json:"not,omitempty"`
+
+	Field string   `json:"field,omitempty"`
+	Op    Operator `json:"op,omitempty"`
+	Value any      `json:"value,omitempty"`
+}
+
+func (node FilterNode) String() string {
+	content, err := json.Marshal(node)
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
+
+// ParseFilter
+// numbers are decoded as int64 if possible, otherwise float64
+func ParseFilter(content string) (*FilterNode, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	node := new(FilterNode)
+	err := decoder.Decode(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func filterValueOf(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		values := make([]any, len(v))
+		for i, item := range v {
+			values[i] = filterValueOf(item)
+		}
+		return values
+	default:
+		return v
+	}
+}
+
+func compileFilterLeaf(node *FilterNode, columns map[string]string) (string, []any, error) {
+	column, ok := columns[node.Field]
+	if !ok {
+		return "", nil, fmt.Errorf("field %s is not filterable", node.Field)
+	}
+
+	if !slices.Contains(Operators, node.Op) {
+		return "", nil, fmt.Errorf("operator %s is not a valid operator", node.Op)
+	}
+
+	value := filterValueOf(node.Value)
+	array, isArray := value.([]any)
+
+	switch node.Op {
+	case OperatorNull, OperatorNNull:
+		return fmt.Sprintf("`%s` %s", column, node.Op), nil, nil
+	case OperatorIn, OperatorNotIn:
+		if len(array) == 0 {
+			return "", nil, fmt.Errorf("value of %s should be a non-empty array", node.Field)
+		}
+		return fmt.Sprintf("`%s` %s ?", column, node.Op), []any{array}, nil
+	case OperatorBetween, OperatorNotBetween:
+		if len(array) != 2 {
+			return "", nil, fmt.Errorf("value of %s should be an array of 2 elements", node.Field)
+		}
+		return fmt.Sprintf("`%s` %s ? AND ?", column, node.Op), array, nil
+	}
+
+	if _, isObject := value.(map[string]any); value == nil || isArray || isObject {
+		return "", nil, fmt.Errorf("value of %s should be a string, number or boolean", node.Field)
+	}
+
+	return fmt.Sprintf("`%s` %s ?", column, node.Op), []any{value}, nil
+}
+
+func compileFilter(node *FilterNode, columns map[string]string, depth int) (string, []any, error) {
+	if depth > DefaultFilterMaxDepth {
+		return "", nil, TooDeepFilterError
+	}
+
+	kinds := 0
+	for _, set := range []bool{len(node.And) > 0, len(node.Or) > 0, node.Not != nil, node.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return "", nil, InvalidFilterNodeError
+	}
+
+	if node.Field != "" {
+		return compileFilterLeaf(node, columns)
+	}
+
+	if node.Not != nil {
+		sql, vars, err := compileFilter(node.Not, columns, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		if !strings.HasPrefix(sql, "(") {
+			sql = "(" + sql + ")"
+		}
+		return "NOT " + sql, vars, nil
+	}
+
+	children, separator := node.And, " AND "
+	if len(node.Or) > 0 {
+		children, separator = node.Or, " OR "
+	}
+
+	sqls := make([]string, len(children))
+	var vars []any
+	for i := range children {
+		sql, childVars, err := compileFilter(&children[i], columns, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		sqls[i] = sql
+		vars = append(vars, childVars...)
+	}
+
+	return "(" + strings.Join(sqls, separator) + ")", vars, nil
+}
+
+// CompileFilter
+// compiles node into a parameterized condition,
+// columns: field names those can be used in node to database columns
+func CompileFilter(node *FilterNode, columns map[string]string) (string, []any, error) {
+	sql, vars, err := compileFilter(node, columns, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasPrefix(sql, "(") {
+		sql = "(" + sql + ")"
+	}
+	return sql, vars, nil
+}
+
+// NewFilterSearchHandler
+// columns: field names those can be used in FilterNode to database columns,
+// malformed or disallowed filters are returned as FilterError
+func NewFilterSearchHandler(columns map[string]string) SearchHandler {
+	return func(db *gorm.DB, values []string, _ *gin.Context) (*gorm.DB, error) {
+		value, ok := PickFirstValuableString(values)
+		if !ok {
+			return db, nil
+		}
+
+		node, err := ParseFilter(value)
+		if err != nil {
+			return nil, &FilterError{Err: err}
+		}
+
+		sql, vars, err := CompileFilter(node, columns)
+		if err != nil {
+			return nil, &FilterError{Err: err}
+		}
+
+		return db.Where(sql, vars...), nil
+	}
+}
@@ -0,0 +1,79 @@
+package gocrud
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCompileFilter(t *testing.T) {
+	columns := map[string]string{
+		"status":   "status",
+		"priority": "priority",
+		"name":     "name",
+	}
+
+	node, err := ParseFilter(`{"or": [
+		{"field": "status", "op": "=", "value": "A"},
+		{"and": [
+			{"field": "priority", "op": ">", "value": 5},
+			{"not": {"field": "name", "op": "LIKE", "value": "x%"}},
+			{"field": "priority", "op": "BETWEEN", "value": [1, 10.5]},
+			{"field": "status", "op": "IN", "value": ["B", "C"]},
+			{"field": "name", "op": "IS NOT NULL"}
+		]}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sql, vars, err := CompileFilter(node, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedSQL := "(`status` = ? OR (`priority` > ? AND NOT (`name` LIKE ?) AND `priority` BETWEEN ? AND ? AND `status` IN ? AND `name` IS NOT NULL))"
+	if sql != expectedSQL {
+		t.Fatalf("expected %s, got %s", expectedSQL, sql)
+	}
+
+	if len(vars) != 6 {
+		t.Fatalf("expected 6 vars, got %d", len(vars))
+	} else if vars[1] != int64(5) || vars[3] != int64(1) || vars[4] != 10.5 {
+		t.Fatalf("unexpected numbers %v", vars)
+	} else if array, ok := vars[5].([]any); !ok || !slices.Equal(array, []any{"B", "C"}) {
+		t.Fatalf("unexpected array %v", vars[5])
+	}
+
+	sql, _, err = CompileFilter(&FilterNode{Field: "name", Op: OperatorEqual, Value: "a"}, columns)
+	if err != nil {
+		t.Fatal(err)
+	} else if sql != "(`name` = ?)" {
+		t.Fatalf("unexpected sql %s", sql)
+	}
+
+	invalidNodes := []FilterNode{
+		{},
+		{Field: "secret", Op: OperatorEqual, Value: "a"},
+		{Field: "name", Op: "; DROP TABLE users", Value: "a"},
+		{Field: "name", Op: OperatorEqual},
+		{Field: "name", Op: OperatorIn, Value: "a"},
+		{Field: "name", Op: OperatorBetween, Value: []any{1}},
+		{Field: "name", Op: OperatorEqual, Value: "a", Or: []FilterNode{{Field: "name", Op: OperatorNull}}},
+	}
+	for _, invalidNode := range invalidNodes {
+		_, _, err = CompileFilter(&invalidNode, columns)
+		if err == nil {
+			t.Fatalf("expected error for %s", invalidNode)
+		}
+	}
+
+	deep := FilterNode{Field: "name", Op: OperatorNull}
+	for range DefaultFilterMaxDepth {
+		deep = FilterNode{Not: &deep}
+	}
+	_, _, err = CompileFilter(&deep, columns)
+	if !errors.Is(err, TooDeepFilterError) {
+		t.Fatalf("expected TooDeepFilterError, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"reflect"
 
 	"github.com/gin-gonic/gin"
 )
@@ -58,9 +59,35 @@ type HttpFileSystemConfig struct {
 	OnFileDigested func(digest FileDigest, saltyDigest FileSaltyDigest) (*HttpFile, error)
 	OnFileSaved    func(file *HttpFile) error
 
+	// Bind
+	// returns the config of a request, such as callbacks scoped by the tenant of the request,
+	// the config itself will be used if it returns nil
+	Bind func(context *gin.Context) (*HttpFileSystemConfig, error)
+
+	// OpenAPI
+	// documents download and upload routes
+	OpenAPI *OpenAPI
+
 	Coder Coder
 }
 
+// boundTo
+// returns the config bound to context with Bind
+func (c *HttpFileSystemConfig) boundTo(context *gin.Context) (*HttpFileSystemConfig, error) {
+	if c.Bind == nil {
+		return c, nil
+	}
+
+	bound, err := c.Bind(context)
+	if err != nil {
+		return nil, err
+	} else if bound == nil {
+		return c, nil
+	}
+
+	return bound, nil
+}
+
 func NewHttpFileSystemController(group *gin.RouterGroup, folder string, config *HttpFileSystemConfig) error {
 	if config == nil {
 		config = &HttpFileSystemConfig{Coder: RestCoder}
@@ -78,13 +105,19 @@ func NewHttpFileSystemController(group *gin.RouterGroup, folder string, config *
 		}
 
 		group.GET("/*filepath", func(context *gin.Context) {
-			httpFile, err := config.OnFileReview(FileName(context.Param("filepath")))
+			bound, err := config.boundTo(context)
 			if err != nil {
-				MakeErrorResponse(context, config.Coder.InternalServerError(), err)
+				MakeErrorResponse(context, config.Coder.Forbidden(), err)
+				return
+			}
+
+			httpFile, err := bound.OnFileReview(FileName(context.Param("filepath")))
+			if err != nil {
+				MakeErrorResponse(context, bound.Coder.InternalServerError(), err)
 				return
 			}
 			if httpFile == nil {
-				MakeErrorResponse(context, config.Coder.NotFound(), http.StatusText(http.StatusNotFound))
+				MakeErrorResponse(context, bound.Coder.NotFound(), http.StatusText(http.StatusNotFound))
 				return
 			}
 
@@ -92,10 +125,10 @@ func NewHttpFileSystemController(group *gin.RouterGroup, folder string, config *
 			file, err := os.Open(filePath)
 			if err != nil {
 				if os.IsNotExist(err) {
-					MakeErrorResponse(context, config.Coder.NotFound(), http.StatusText(http.StatusNotFound))
+					MakeErrorResponse(context, bound.Coder.NotFound(), http.StatusText(http.StatusNotFound))
 					return
 				}
-				MakeErrorResponse(context, config.Coder.InternalServerError(), err)
+				MakeErrorResponse(context, bound.Coder.InternalServerError(), err)
 				return
 			}
 			defer func() {
@@ -104,7 +137,7 @@ func NewHttpFileSystemController(group *gin.RouterGroup, folder string, config *
 
 			serveFunc, err := NewDareHttpServeFunc(file, httpFile)
 			if err != nil {
-				MakeErrorResponse(context, config.Coder.InternalServerError(), err)
+				MakeErrorResponse(context, bound.Coder.InternalServerError(), err)
 				return
 			}
 
@@ -113,8 +146,14 @@ func NewHttpFileSystemController(group *gin.RouterGroup, folder string, config *
 	}
 
 	uploadHandler := func(context *gin.Context) {
-		if !config.AllowUpload {
-			MakeErrorResponse(context, config.Coder.MethodNotAllowed(), ErrorUploadNotAllowed)
+		bound, err := config.boundTo(context)
+		if err != nil {
+			MakeErrorResponse(context, config.Coder.Forbidden(), err)
+			return
+		}
+
+		if !bound.AllowUpload {
+			MakeErrorResponse(context, bound.Coder.MethodNotAllowed(), ErrorUploadNotAllowed)
 			return
 		}
 
@@ -125,26 +164,26 @@ func NewHttpFileSystemController(group *gin.RouterGroup, folder string, config *
 				Ext:            path.Ext(path.Base(context.Param("filepath"))),
 				Size:           FileSize(context.Request.ContentLength),
 				Validigest:     FileDigest(context.GetHeader(XFileDigest)),
-				MasterKey:      config.FileMasterKey,
-				HashSalt:       config.FileHashSalt,
-				OnFileDigested: config.OnFileDigested,
+				MasterKey:      bound.FileMasterKey,
+				HashSalt:       bound.FileHashSalt,
+				OnFileDigested: bound.OnFileDigested,
 			},
 		)
 		if err != nil {
-			MakeErrorResponse(context, config.Coder.InternalServerError(), err)
+			MakeErrorResponse(context, bound.Coder.InternalServerError(), err)
 			return
 		}
 
-		if config.OnFileSaved != nil {
-			err := config.OnFileSaved(file)
+		if bound.OnFileSaved != nil {
+			err := bound.OnFileSaved(file)
 			if err != nil {
-				MakeErrorResponse(context, config.Coder.InternalServerError(), err)
+				MakeErrorResponse(context, bound.Coder.InternalServerError(), err)
 				return
 			}
 		}
 
 		context.JSON(http.StatusOK, R[string]{
-			Code: config.Coder.OK(),
+			Code: bound.Coder.OK(),
 			Data: string(file.Name),
 		})
 	}
@@ -152,5 +191,23 @@ func NewHttpFileSystemController(group *gin.RouterGroup, folder string, config *
 	group.POST("/*filepath", uploadHandler)
 	group.PUT("/*filepath", uploadHandler)
 
+	if config.OpenAPI != nil {
+		tag := openAPITagOf(group)
+		filepath := openAPIPathOf(group, "/*filepath")
+
+		config.OpenAPI.Add(OpenAPIRoute{
+			Method: http.MethodGet, Path: filepath, Tag: tag, Summary: "download",
+			ContentTypes: []string{"application/octet-stream"},
+		})
+		for _, method := range []string{http.MethodPost, http.MethodPut} {
+			config.OpenAPI.Add(OpenAPIRoute{
+				Method: method, Path: filepath, Tag: tag, Summary: "upload",
+				HeaderKeys:       []string{XFileDigest},
+				BodyContentTypes: []string{"application/octet-stream"},
+				Data:             reflect.TypeFor[string](),
+			})
+		}
+	}
+
 	return nil
 }
@@ -54,6 +54,15 @@ type HttpFileSystemObjectConfig[T any] struct {
 	AllowUpload   bool
 	FileMasterKey FileMasterKey
 	FileHashSalt  FileHashSalt
+
+	// Tenancy
+	// looks up and saves objects of the tenant of the request only, requires FileMasterKey.
+	// the same file of different tenants shares the stored file, and requires the tenant field to be a primary key of T as well.
+	Tenancy *Tenancy
+
+	// OpenAPI
+	// see HttpFileSystemConfig.OpenAPI
+	OpenAPI *OpenAPI
 }
 
 // NewHttpFileSystemConfig
@@ -72,6 +81,7 @@ func (h *HttpFileSystemObjectConfig[T]) NewHttpFileSystemConfig(
 		AllowUpload:   baseConfig.AllowUpload,
 		FileMasterKey: baseConfig.FileMasterKey,
 		FileHashSalt:  baseConfig.FileHashSalt,
+		OpenAPI:       baseConfig.OpenAPI,
 	}
 
 	if baseStructFieldName == "" {
@@ -155,69 +165,128 @@ func (h *HttpFileSystemObjectConfig[T]) NewHttpFileSystemConfig(
 		return nil
 	}
 
-	config.OnFileReview = func(filenameOrSaltyDigest FileName) (*HttpFile, error) {
-		saltyDigest := path.Base(string(filenameOrSaltyDigest))
-		dotIndex := strings.Index(saltyDigest, ".")
-		if dotIndex >= 0 {
-			saltyDigest = saltyDigest[:dotIndex]
+	var tenantColumn string
+	if baseConfig.Tenancy != nil {
+		tenantColumn, err = tenantColumnOf[T](db, baseConfig.Tenancy)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		if saltyDigest == "" {
-			return nil, nil
+	// bindCallbacks
+	// fills callbacks of c with db, objects are scoped by tenant if Tenancy is specified
+	bindCallbacks := func(c *HttpFileSystemConfig, db *gorm.DB, tenant any) {
+		repo := func() *gorm.DB {
+			if baseConfig.Tenancy == nil {
+				return db.Model(new(T))
+			}
+			return baseConfig.Tenancy.Scope(db.Model(new(T)), tenantColumn, tenant)
 		}
 
-		var records []T
-		if err = db.Model(new(T)).Where("`salty_digest` = ?", saltyDigest).Find(&records).Error; err != nil {
-			logger.Error().Printf("OnFileReview: failed to find file object %s:%s:%s, err: %s", folder, filenameOrSaltyDigest, saltyDigest, err)
-			return nil, err
-		}
+		c.OnFileReview = func(filenameOrSaltyDigest FileName) (*HttpFile, error) {
+			saltyDigest := path.Base(string(filenameOrSaltyDigest))
+			dotIndex := strings.Index(saltyDigest, ".")
+			if dotIndex >= 0 {
+				saltyDigest = saltyDigest[:dotIndex]
+			}
 
-		if len(records) == 0 {
-			return nil, nil
-		}
+			if saltyDigest == "" {
+				return nil, nil
+			}
 
-		obj, err := getObjectBase(&records[0])
-		if err != nil {
-			return nil, err
-		}
+			var records []T
+			if err := repo().Where("`salty_digest` = ?", saltyDigest).Find(&records).Error; err != nil {
+				logger.Error().Printf("OnFileReview: failed to find file object %s:%s:%s, err: %s", folder, filenameOrSaltyDigest, saltyDigest, err)
+				return nil, err
+			}
 
-		return obj.ToHttpFile(), nil
-	}
+			if len(records) == 0 {
+				return nil, nil
+			}
 
-	config.OnFileDigested = func(_ FileDigest, saltyDigest FileSaltyDigest) (*HttpFile, error) {
-		var records []T
-		if err = db.Model(new(T)).Where("`salty_digest` = ?", saltyDigest).Find(&records).Error; err != nil {
-			logger.Error().Printf("OnFileDigested: failed to find file object %s:%s, err: %s", folder, saltyDigest, err)
-			return nil, err
-		}
+			obj, err := getObjectBase(&records[0])
+			if err != nil {
+				return nil, err
+			}
 
-		if len(records) == 0 {
-			return nil, nil
+			return obj.ToHttpFile(), nil
 		}
 
-		obj, err := getObjectBase(&records[0])
-		if err != nil {
-			return nil, err
+		c.OnFileDigested = func(_ FileDigest, saltyDigest FileSaltyDigest) (*HttpFile, error) {
+			var records []T
+			if err := repo().Where("`salty_digest` = ?", saltyDigest).Find(&records).Error; err != nil {
+				logger.Error().Printf("OnFileDigested: failed to find file object %s:%s, err: %s", folder, saltyDigest, err)
+				return nil, err
+			}
+
+			// the same file has been saved by another tenant, its key is reused, because the file will not be saved again
+			if len(records) == 0 && baseConfig.Tenancy != nil {
+				if err := db.Model(new(T)).Where("`salty_digest` = ?", saltyDigest).Limit(1).Find(&records).Error; err != nil {
+					logger.Error().Printf("OnFileDigested: failed to find file object of other tenants %s:%s, err: %s", folder, saltyDigest, err)
+					return nil, err
+				}
+			}
+
+			if len(records) == 0 {
+				return nil, nil
+			}
+
+			obj, err := getObjectBase(&records[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return obj.ToHttpFile(), nil
 		}
 
-		return obj.ToHttpFile(), nil
-	}
+		c.OnFileSaved = func(file *HttpFile) error {
+			obj := new(HttpFileSystemObjectBase)
+			obj.FromHttpFile(file)
 
-	config.OnFileSaved = func(file *HttpFile) error {
-		obj := new(HttpFileSystemObjectBase)
-		obj.FromHttpFile(file)
+			var record T
+			err := setObjectBase(&record, obj)
+			if err != nil {
+				return err
+			}
 
-		var record T
-		err = setObjectBase(&record, obj)
-		if err != nil {
-			return err
+			if baseConfig.Tenancy == nil {
+				return db.Model(&record).Save(&record).Error
+			}
+
+			err = baseConfig.Tenancy.Stamp(&record, tenant)
+			if err != nil {
+				return err
+			}
+
+			var count int64
+			err = repo().Where("`salty_digest` = ?", obj.SaltyDigest).Count(&count).Error
+			if err != nil || count > 0 {
+				return err
+			}
+
+			// created instead of saved, so that the object of another tenant will never be overridden
+			return db.Model(&record).Create(&record).Error
 		}
+	}
+
+	bindCallbacks(config, db, nil)
 
-		if err := db.Model(&record).Save(&record).Error; err != nil {
-			return err
+	// callbacks of a request are bound to the context of request, and scoped by its tenant
+	config.Bind = func(context *gin.Context) (*HttpFileSystemConfig, error) {
+		var tenant any
+		if baseConfig.Tenancy != nil {
+			var err error
+			tenant, err = baseConfig.Tenancy.Tenant(context)
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		return nil
+		bound := *config
+		bound.Bind = nil
+		bindCallbacks(&bound, WithRequestContext(db, context), tenant)
+
+		return &bound, nil
 	}
 
 	return config, nil
@@ -5,8 +5,10 @@ go 1.25.0
 require (
 	github.com/allape/gocensored v0.0.0-20241204084855-9b73e0aa29ea
 	github.com/allape/gogger v1.0.0
+	github.com/allape/gosalty v0.0.0-20241204072201-5664235f50dc
 	github.com/gin-contrib/cors v1.7.7
 	github.com/gin-gonic/gin v1.12.0
+	github.com/go-playground/validator/v10 v10.30.3
 	github.com/minio/sio v0.5.1
 	golang.org/x/crypto v0.54.0
 	gorm.io/driver/sqlite v1.6.0
@@ -16,7 +18,6 @@ require (
 require (
 	github.com/allape/goenv v0.0.0-20241202051618-ce41afb81ebf // indirect
 	github.com/allape/gomysqlaes v0.0.0-20241202054245-51a6dcfcbd79 // indirect
-	github.com/allape/gosalty v0.0.0-20241204072201-5664235f50dc // indirect
 	github.com/bytedance/gopkg v0.1.4 // indirect
 	github.com/bytedance/sonic v1.15.2 // indirect
 	github.com/bytedance/sonic/loader v0.5.2 // indirect
@@ -25,7 +26,6 @@ require (
 	github.com/gin-contrib/sse v1.1.1 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
-	github.com/go-playground/validator/v10 v10.30.3 // indirect
 	github.com/goccy/go-json v0.10.6 // indirect
 	github.com/goccy/go-yaml v1.19.2 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
@@ -107,6 +107,49 @@ func GetJSONFieldNameOf[T any](fields ...string) ([]string, error) {
 	return jsonFieldNames, nil
 }
 
+// JSONFieldsOf
+// json field name to struct field of T, fields of embedded structs are included
+func JSONFieldsOf[T any]() map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for _, field := range reflect.VisibleFields(reflect.TypeFor[T]()) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+
+		name := strings.TrimSpace(strings.Split(jsonTag, ",")[0])
+		if name == "" {
+			name = field.Name
+		}
+
+		if _, ok := fields[name]; !ok {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+// GetObjectFieldNameOf
+// reverse of GetJSONFieldNameOf, fields of embedded structs are included
+func GetObjectFieldNameOf[T any](jsonFields ...string) ([]string, error) {
+	fields := JSONFieldsOf[T]()
+
+	objectFieldNames := make([]string, len(jsonFields))
+	for i, jsonField := range jsonFields {
+		field, ok := fields[jsonField]
+		if !ok {
+			return nil, fmt.Errorf("field %s not found", jsonField)
+		}
+		objectFieldNames[i] = field.Name
+	}
+
+	return objectFieldNames, nil
+}
+
 func IDsJoin(ids []ID, sep string) string {
 	strIds := make([]string, len(ids))
 	for i, id := range ids {
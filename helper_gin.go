@@ -1,20 +1,158 @@
 package gocrud
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
 )
 
 var RestCoder = NewDefaultCoder()
 
+const (
+	// ContextKeyRequestID
+	// the request id set by RequestIDHandler
+	ContextKeyRequestID = "gocrud:request-id"
+	HeaderKeyRequestID  = "X-Request-Id"
+	// ContextKeyResponseError
+	// the error responded by MakeErrorResponse, see ResponseErrorOf
+	ContextKeyResponseError = "gocrud:response-error"
+)
+
 type R[T any] struct {
-	Code    Code   `json:"c"`
-	Message string `json:"m,omitempty"`
-	Data    T      `json:"d"`
+	Code    Code           `json:"c"`
+	Message string         `json:"m,omitempty"`
+	Data    T              `json:"d"`
+	Error   *ResponseError `json:"e,omitempty"`
+}
+
+// ErrorDetails
+// describes which fields or records caused an error
+type ErrorDetails struct {
+	Fields FieldErrors `json:"fields,omitempty"`
+	IDs    Keys        `json:"ids,omitempty"`
+}
+
+// ResponseError
+// responded in R.Error by MakeErrorResponse, and returned by MakeJSONRequest when the code of response is not OK
+type ResponseError struct {
+	Code      Code          `json:"code"`
+	Message   string        `json:"message"`
+	Details   *ErrorDetails `json:"details,omitempty"`
+	RequestID string        `json:"requestId,omitempty"`
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+type detailedError struct {
+	error
+	details *ErrorDetails
+}
+
+func (e *detailedError) Unwrap() error {
+	return e.error
+}
+
+// WithDetails
+// attaches details to err, which will be responded in ResponseError.Details, errors.Is still works on the returned error
+func WithDetails(err error, details *ErrorDetails) error {
+	return &detailedError{error: err, details: details}
+}
+
+// detailsOf
+// extracts ErrorDetails from err, such as FieldErrors, BatchSaveErrors or errors returned by WithDetails
+func detailsOf(err error) *ErrorDetails {
+	var responseError *ResponseError
+	if errors.As(err, &responseError) && responseError.Details != nil {
+		return responseError.Details
+	}
+
+	var detailed *detailedError
+	if errors.As(err, &detailed) {
+		return detailed.details
+	}
+
+	var fieldErrors FieldErrors
+	if errors.As(err, &fieldErrors) {
+		return &ErrorDetails{Fields: fieldErrors}
+	}
+
+	var batchSaveErrors BatchSaveErrors
+	if errors.As(err, &batchSaveErrors) {
+		details := &ErrorDetails{}
+		for _, batchSaveError := range batchSaveErrors {
+			details.Fields = append(details.Fields, batchSaveError.Fields.WithPrefix(fmt.Sprintf("[%d]", batchSaveError.Index))...)
+		}
+		if len(details.Fields) > 0 {
+			return details
+		}
+	}
+
+	return nil
+}
+
+// RequestIDOf
+// returns the request id set by RequestIDHandler, or the one in header HeaderKeyRequestID
+func RequestIDOf(context *gin.Context) string {
+	if requestID := context.GetString(ContextKeyRequestID); requestID != "" {
+		return requestID
+	}
+	if context.Request == nil {
+		return ""
+	}
+	return context.GetHeader(HeaderKeyRequestID)
+}
+
+// ResponseErrorOf
+// the error responded with MakeErrorResponse or MakeErrorDataResponse to context, false if there is none
+func ResponseErrorOf(context *gin.Context) (*ResponseError, bool) {
+	value, ok := context.Get(ContextKeyResponseError)
+	if !ok {
+		return nil, false
+	}
+	responseError, ok := value.(*ResponseError)
+	return responseError, ok
+}
+
+// RequestIDHandler
+// reuses the request id in header HeaderKeyRequestID or generates one, and echoes it in response header
+func RequestIDHandler() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		requestID := context.GetHeader(HeaderKeyRequestID)
+		if requestID == "" {
+			requestID = NewRequestID()
+		}
+		context.Set(ContextKeyRequestID, requestID)
+		context.Header(HeaderKeyRequestID, requestID)
+		context.Next()
+	}
+}
+
+// NewRequestID
+// returns a random hex string
+func NewRequestID() string {
+	bytes := make([]byte, 16)
+	_, _ = rand.Read(bytes)
+	return hex.EncodeToString(bytes)
 }
 
 func MakeErrorResponse(context *gin.Context, code Code, err any) {
+	MakeErrorDataResponse[any](context, code, err, nil)
+}
+
+// MakeErrorDataResponse
+// same as MakeErrorResponse, with extra data for describing the error
+func MakeErrorDataResponse[T any](context *gin.Context, code Code, err any, data T) {
 	message := http.StatusText(http.StatusInternalServerError)
 
 	if err != nil {
@@ -35,9 +173,24 @@ func MakeErrorResponse(context *gin.Context, code Code, err any) {
 		}
 	}
 
-	context.AbortWithStatusJSON(http.StatusOK, R[any]{
-		Code:    Ternary(code == "", RestCoder.InternalServerError(), code),
+	code = Ternary(code == "", RestCoder.InternalServerError(), code)
+
+	responseError := &ResponseError{
+		Code:      code,
+		Message:   message,
+		RequestID: RequestIDOf(context),
+	}
+	if e, ok := err.(error); ok {
+		responseError.Details = detailsOf(e)
+	}
+
+	context.Set(ContextKeyResponseError, responseError)
+
+	context.AbortWithStatusJSON(http.StatusOK, R[T]{
+		Code:    code,
 		Message: message,
+		Data:    data,
+		Error:   responseError,
 	})
 }
 
@@ -62,3 +215,42 @@ func RecoveryHandler(responseFullError bool) gin.HandlerFunc {
 		)
 	})
 }
+
+// BindJSON
+// decodes body of context into obj as context.ShouldBindJSON does, but leaves validation to Validator
+func BindJSON(context *gin.Context, obj any) error {
+	if context.Request == nil || context.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+
+	decoder := json.NewDecoder(context.Request.Body)
+	if binding.EnableDecoderUseNumber {
+		decoder.UseNumber()
+	}
+	if binding.EnableDecoderDisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	return decoder.Decode(obj)
+}
+
+// WithRequestContext
+// binds db to the context of request, so that queries are canceled once the client disconnects or the request times out
+func WithRequestContext(db *gorm.DB, c *gin.Context) *gorm.DB {
+	if c == nil || c.Request == nil {
+		return db
+	}
+	return db.WithContext(c.Request.Context())
+}
+
+// QueryTimeoutHandler
+// limits the context of request with timeout, which cancels database calls bound by WithRequestContext
+func QueryTimeoutHandler(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -4,18 +4,27 @@ import (
 	"fmt"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
-func GetDatabaseFieldNameOf[T any](db *gorm.DB, fields ...string) ([]string, error) {
+func GetSchemaOf[T any](db *gorm.DB) (*schema.Schema, error) {
 	stmt := &gorm.Statement{DB: db}
 	err := stmt.Parse(new(T))
 	if err != nil {
 		return nil, err
 	}
+	return stmt.Schema, nil
+}
+
+func GetDatabaseFieldNameOf[T any](db *gorm.DB, fields ...string) ([]string, error) {
+	s, err := GetSchemaOf[T](db)
+	if err != nil {
+		return nil, err
+	}
 
 	dbFields := make([]string, len(fields))
 	for i, field := range fields {
-		dbField := stmt.Schema.LookUpField(field)
+		dbField := s.LookUpField(field)
 		if dbField == nil {
 			return nil, fmt.Errorf("field %s not found", field)
 		}
@@ -0,0 +1,472 @@
+package gocrud
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Key
+// a primary key, such as ID, a string, or a UUID which implements encoding.TextUnmarshaler
+type Key = any
+
+var (
+	EmptyKeyError           = errors.New("key is empty")
+	UnsupportedKeyTypeError = errors.New("unsupported key type")
+	InvalidKeyError         = errors.New("invalid key")
+	NoPrimaryKeyError       = errors.New("no primary key")
+)
+
+// ContextKeyKeys
+// keys of the route params of a delete or restore, parsed by Crud before OnDelete and OnRestore
+const ContextKeyKeys = "gocrud:crud:keys"
+
+// GetKeys
+// returns keys set by SetKeys, false if there is none
+func GetKeys(context *gin.Context) ([]Key, bool) {
+	if value, ok := context.Get(ContextKeyKeys); ok {
+		return value.([]Key), true
+	}
+	return nil, false
+}
+
+func SetKeys(context *gin.Context, keys []Key) {
+	context.Set(ContextKeyKeys, keys)
+}
+
+// CompositeKey
+// database column to value of a key of multiple primary fields, such as `{"org_id": 1, "code": "a"}`
+type CompositeKey map[string]Key
+
+// String
+// columns and formatted values in query form sorted by columns, such as `code=a&org_id=1`
+func (key CompositeKey) String() string {
+	values := url.Values{}
+	for column, value := range key {
+		values.Set(column, FormatKey(value))
+	}
+	return values.Encode()
+}
+
+// KeyCodec
+// converts keys from and to strings in routes and queries, such as `/one/:id` or `?in_id=1,2`
+type KeyCodec interface {
+	// Parse
+	// returns a key of the type of the codec
+	Parse(value string) (Key, error)
+	Format(key Key) string
+}
+
+// keyCodecs
+// reflect.Type to KeyCodec, see RegisterKeyCodec
+var keyCodecs sync.Map
+
+// RegisterKeyCodec
+// uses codec for keys of K, instead of the default one
+func RegisterKeyCodec[K any](codec KeyCodec) {
+	keyCodecs.Store(reflect.TypeFor[K](), codec)
+}
+
+// reflectKeyCodec
+// the default codec of integers, strings, and types implement encoding.TextUnmarshaler
+type reflectKeyCodec struct {
+	t reflect.Type
+}
+
+func (c reflectKeyCodec) Parse(value string) (Key, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, EmptyKeyError
+	}
+
+	key := reflect.New(c.t)
+
+	if unmarshaler, ok := key.Interface().(encoding.TextUnmarshaler); ok {
+		err := unmarshaler.UnmarshalText([]byte(value))
+		if err != nil {
+			return nil, err
+		}
+		return key.Elem().Interface(), nil
+	}
+
+	switch c.t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, c.t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		key.Elem().SetUint(n)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, c.t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		key.Elem().SetInt(n)
+	case reflect.String:
+		key.Elem().SetString(value)
+	default:
+		return nil, fmt.Errorf("%w: %s", UnsupportedKeyTypeError, c.t)
+	}
+
+	return key.Elem().Interface(), nil
+}
+
+func (c reflectKeyCodec) Format(key Key) string {
+	if marshaler, ok := key.(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(key)
+}
+
+func isSupportedKeyType(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.String:
+		return true
+	default:
+		return false
+	}
+}
+
+// KeyCodecFor
+// returns the registered codec of t, or the default one if t is an integer, a string or an encoding.TextUnmarshaler
+func KeyCodecFor(t reflect.Type) (KeyCodec, error) {
+	if codec, ok := keyCodecs.Load(t); ok {
+		return codec.(KeyCodec), nil
+	}
+
+	if !isSupportedKeyType(t) {
+		return nil, fmt.Errorf("%w: %s", UnsupportedKeyTypeError, t)
+	}
+
+	return reflectKeyCodec{t: t}, nil
+}
+
+// KeyCodecOf
+// returns KeyCodecFor the type of field ID of T, or of ID if T has no such field
+func KeyCodecOf[T any]() (KeyCodec, error) {
+	if field, ok := reflect.TypeFor[T]().FieldByName("ID"); ok {
+		return KeyCodecFor(field.Type)
+	}
+	return KeyCodecFor(reflect.TypeFor[ID]())
+}
+
+// FormatKey
+// formats key with KeyCodecFor its type
+func FormatKey(key Key) string {
+	if key == nil {
+		return ""
+	}
+	codec, err := KeyCodecFor(reflect.TypeOf(key))
+	if err != nil {
+		return fmt.Sprint(key)
+	}
+	return codec.Format(key)
+}
+
+// IsZeroKey
+// new records have zero keys, a CompositeKey is zero if any of its values is zero
+func IsZeroKey(key Key) bool {
+	if composite, ok := key.(CompositeKey); ok {
+		if len(composite) == 0 {
+			return true
+		}
+		for _, value := range composite {
+			if IsZeroKey(value) {
+				return true
+			}
+		}
+		return false
+	}
+	return key == nil || reflect.ValueOf(key).IsZero()
+}
+
+// Keys
+// keys in json, integers are decoded as ID, strings as string and objects as CompositeKey,
+// such as `[1, "0190b6f2-...", {"org_id": 1, "code": "a"}]`
+type Keys []Key
+
+func (keys *Keys) UnmarshalJSON(data []byte) error {
+	var raws []json.RawMessage
+	err := json.Unmarshal(data, &raws)
+	if err != nil {
+		return err
+	}
+
+	decoded := make(Keys, len(raws))
+	for i, raw := range raws {
+		decoded[i], err = decodeKey(raw)
+		if err != nil {
+			return err
+		}
+	}
+
+	*keys = decoded
+
+	return nil
+}
+
+func decodeKey(raw json.RawMessage) (Key, error) {
+	var str string
+	if json.Unmarshal(raw, &str) == nil {
+		return str, nil
+	}
+
+	var id ID
+	if json.Unmarshal(raw, &id) == nil {
+		return id, nil
+	}
+
+	var raws map[string]json.RawMessage
+	if json.Unmarshal(raw, &raws) == nil && raws != nil {
+		composite := make(CompositeKey, len(raws))
+		for column, value := range raws {
+			key, err := decodeKey(value)
+			if err != nil {
+				return nil, err
+			}
+			composite[column] = key
+		}
+		return composite, nil
+	}
+
+	var key any
+	err := json.Unmarshal(raw, &key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ToKeys
+// converts typed keys to Keys, such as `ToKeys(ids...)` for a []ID
+func ToKeys[K any](keys ...K) Keys {
+	converted := make(Keys, len(keys))
+	for i, key := range keys {
+		converted[i] = key
+	}
+	return converted
+}
+
+// KeysJoin
+// formats keys with FormatKey, and joins them with sep
+func KeysJoin(keys []Key, sep string) string {
+	strKeys := make([]string, len(keys))
+	for i, key := range keys {
+		strKeys[i] = FormatKey(key)
+	}
+	return strings.Join(strKeys, sep)
+}
+
+// KeysFromCommaSeparatedString
+// parses comma separated keys with codec, invalid ones are dropped
+func KeysFromCommaSeparatedString(codec KeyCodec, css string) []Key {
+	var keys []Key
+	MapFuncOverCommaSeparatedString(func(s string) {
+		key, err := codec.Parse(s)
+		if err != nil {
+			return
+		}
+		keys = append(keys, key)
+	}, css)
+	return keys
+}
+
+// KeysOf
+// KeysFromCommaSeparatedString with KeyCodecOf T, nil if the key type of T is unsupported
+func KeysOf[T any](css string) []Key {
+	codec, err := KeyCodecOf[T]()
+	if err != nil {
+		return nil
+	}
+	return KeysFromCommaSeparatedString(codec, css)
+}
+
+// PrimaryKey
+// primary fields of a model parsed by gorm, keys of a model with multiple primary fields are CompositeKey
+type PrimaryKey struct {
+	Fields []*schema.Field
+	codecs []KeyCodec
+}
+
+// PrimaryKeyOf
+// returns the PrimaryKey of T, with KeyCodecFor the type of every primary field
+func PrimaryKeyOf[T any](db *gorm.DB) (*PrimaryKey, error) {
+	s, err := GetSchemaOf[T](db)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.PrimaryFields) == 0 {
+		return nil, fmt.Errorf("%w: %s", NoPrimaryKeyError, s.Name)
+	}
+
+	primaryKey := &PrimaryKey{
+		Fields: s.PrimaryFields,
+		codecs: make([]KeyCodec, len(s.PrimaryFields)),
+	}
+	for i, field := range s.PrimaryFields {
+		primaryKey.codecs[i], err = KeyCodecFor(field.FieldType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Name, err)
+		}
+	}
+
+	return primaryKey, nil
+}
+
+func (pk *PrimaryKey) Composite() bool {
+	return len(pk.Fields) > 1
+}
+
+// Columns
+// database columns of the primary fields, such as `org_id` and `code`
+func (pk *PrimaryKey) Columns() []string {
+	columns := make([]string, len(pk.Fields))
+	for i, field := range pk.Fields {
+		columns[i] = field.DBName
+	}
+	return columns
+}
+
+// Parse
+// values: one for each primary field in order, returns a CompositeKey if there are more than one
+func (pk *PrimaryKey) Parse(values ...string) (Key, error) {
+	if len(values) != len(pk.Fields) {
+		return nil, InvalidKeyError
+	}
+
+	if !pk.Composite() {
+		return pk.codecs[0].Parse(values[0])
+	}
+
+	key := make(CompositeKey, len(pk.Fields))
+	for i, field := range pk.Fields {
+		value, err := pk.codecs[i].Parse(values[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.DBName, err)
+		}
+		key[field.DBName] = value
+	}
+
+	return key, nil
+}
+
+// ParseMany
+// values: comma separated values for each primary field in order, which are zipped into keys,
+// such as `1,1` and `a,b` for keys `{"org_id": 1, "code": "a"}` and `{"org_id": 1, "code": "b"}`,
+// invalid ones are dropped, nil if the counts of values are different
+func (pk *PrimaryKey) ParseMany(values ...string) []Key {
+	if len(values) != len(pk.Fields) {
+		return nil
+	}
+
+	if !pk.Composite() {
+		return KeysFromCommaSeparatedString(pk.codecs[0], values[0])
+	}
+
+	columns := make([][]string, len(values))
+	for i, value := range values {
+		columns[i] = StringArrayFromCommaSeparatedString(value)
+		if len(columns[i]) != len(columns[0]) {
+			return nil
+		}
+	}
+
+	var keys []Key
+	parts := make([]string, len(columns))
+	for j := range columns[0] {
+		for i := range columns {
+			parts[i] = columns[i][j]
+		}
+		key, err := pk.Parse(parts...)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Of
+// returns the key of record, which is a pointer to the model
+func (pk *PrimaryKey) Of(record any) Key {
+	value := reflect.ValueOf(record).Elem()
+
+	if !pk.Composite() {
+		return pk.Fields[0].ReflectValueOf(context.Background(), value).Interface()
+	}
+
+	key := make(CompositeKey, len(pk.Fields))
+	for _, field := range pk.Fields {
+		key[field.DBName] = field.ReflectValueOf(context.Background(), value).Interface()
+	}
+	return key
+}
+
+// Where
+// filters db by keys, with `table`.`id` IN ? for a single primary field,
+// or (`table`.`org_id` = ? AND `table`.`code` = ?) OR ... for CompositeKey
+func (pk *PrimaryKey) Where(db *gorm.DB, keys []Key) *gorm.DB {
+	if !pk.Composite() || len(keys) == 0 {
+		values := make([]any, len(keys))
+		for i, key := range keys {
+			values[i] = key
+		}
+		// nothing matches an empty IN
+		return db.Where(clause.IN{Column: pk.column(pk.Fields[0]), Values: values})
+	}
+
+	ors := make([]clause.Expression, len(keys))
+	for i, key := range keys {
+		composite, ok := key.(CompositeKey)
+		if !ok {
+			return withError(db, fmt.Errorf("%w: %v", InvalidKeyError, key))
+		}
+
+		ands := make([]clause.Expression, len(pk.Fields))
+		for j, field := range pk.Fields {
+			value, ok := composite[field.DBName]
+			if !ok {
+				return withError(db, fmt.Errorf("%w: %s is missing in %s", InvalidKeyError, field.DBName, composite))
+			}
+			ands[j] = clause.Eq{Column: pk.column(field), Value: value}
+		}
+		ors[i] = clause.And(ands...)
+	}
+
+	return db.Where(clause.Or(ors...))
+}
+
+// column
+// column of field qualified by the current table, so that it is not ambiguous in joins
+func (pk *PrimaryKey) column(field *schema.Field) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+}
+
+// withError
+// a new session of db with err, which fails the following query, db itself is untouched
+func withError(db *gorm.DB, err error) *gorm.DB {
+	tx := db.Session(&gorm.Session{})
+	_ = tx.AddError(err)
+	return tx
+}
@@ -0,0 +1,502 @@
+package gocrud
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allape/gogger"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DocumentID
+// a UUIDv7 key, stored as its string form
+type DocumentID [16]byte
+
+func NewDocumentID() DocumentID {
+	var id DocumentID
+	_, _ = rand.Read(id[:])
+	binary.BigEndian.PutUint64(id[:8], uint64(time.Now().UnixMilli())<<16|uint64(id[6])<<8|uint64(id[7]))
+	id[6] = id[6]&0x0f | 0x70
+	id[8] = id[8]&0x3f | 0x80
+	return id
+}
+
+func (id DocumentID) MarshalText() ([]byte, error) {
+	h := hex.EncodeToString(id[:])
+	return []byte(fmt.Sprintf("%s-%s-%s-%s-%s", h[:8], h[8:12], h[12:16], h[16:20], h[20:])), nil
+}
+
+func (id *DocumentID) UnmarshalText(text []byte) error {
+	content, err := hex.DecodeString(strings.ReplaceAll(string(text), "-", ""))
+	if err != nil {
+		return err
+	} else if len(content) != len(id) {
+		return errors.New("invalid uuid")
+	}
+	copy(id[:], content)
+	return nil
+}
+
+func (id DocumentID) Value() (driver.Value, error) {
+	text, err := id.MarshalText()
+	return string(text), err
+}
+
+func (id *DocumentID) Scan(src any) error {
+	switch value := src.(type) {
+	case string:
+		return id.UnmarshalText([]byte(value))
+	case []byte:
+		return id.UnmarshalText(value)
+	default:
+		return fmt.Errorf("unsupported type %T", src)
+	}
+}
+
+type Document struct {
+	ID        DocumentID `json:"id"        gorm:"primaryKey;type:varchar(36)"`
+	Title     string     `json:"title"`
+	UpdatedAt time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+func (d *Document) BeforeCreate(_ *gorm.DB) error {
+	if IsZeroKey(d.ID) {
+		d.ID = NewDocumentID()
+	}
+	return nil
+}
+
+type DocumentLabel struct {
+	DocumentID DocumentID `json:"documentId" gorm:"primaryKey;type:varchar(36)"`
+	Label      string     `json:"label"      gorm:"primaryKey"`
+}
+
+// Membership
+// keyed by (org_id, code)
+type Membership struct {
+	OrgID     ID         `json:"orgId"     gorm:"primaryKey;autoIncrement:false"`
+	Code      string     `json:"code"      gorm:"primaryKey"`
+	Role      string     `json:"role"`
+	UpdatedAt time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+// LabelCode
+// stored in upper case, and formatted in lower case by upperKeyCodec
+type LabelCode string
+
+type upperKeyCodec struct{}
+
+func (upperKeyCodec) Parse(value string) (Key, error) {
+	if value == "" {
+		return nil, EmptyKeyError
+	}
+	return LabelCode(strings.ToUpper(value)), nil
+}
+
+func (upperKeyCodec) Format(key Key) string {
+	return strings.ToLower(fmt.Sprint(key))
+}
+
+func TestKeyCodec(t *testing.T) {
+	codec, err := KeyCodecOf[User]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := codec.Parse("12")
+	if err != nil || key != ID(12) {
+		t.Fatalf("expected ID 12, got %v: %v", key, err)
+	}
+	if _, err = codec.Parse("abc"); err == nil {
+		t.Fatal("expected error of invalid id")
+	}
+
+	codec, err = KeyCodecOf[Document]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := NewDocumentID()
+	key, err = codec.Parse(FormatKey(id))
+	if err != nil || key != id {
+		t.Fatalf("expected %s, got %v: %v", FormatKey(id), key, err)
+	}
+
+	if _, err = KeyCodecFor(reflect.TypeFor[float64]()); !errors.Is(err, UnsupportedKeyTypeError) {
+		t.Fatalf("expected unsupported key type, got %v", err)
+	}
+
+	RegisterKeyCodec[LabelCode](upperKeyCodec{})
+	codec, err = KeyCodecFor(reflect.TypeFor[LabelCode]())
+	if err != nil {
+		t.Fatal(err)
+	} else if keys := KeysFromCommaSeparatedString(codec, "a, b,,c"); KeysJoin(keys, ",") != "a,b,c" || keys[0] != LabelCode("A") {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	var keys Keys
+	err = json.Unmarshal([]byte(`[1, "a"]`), &keys)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 2 || keys[0] != ID(1) || keys[1] != "a" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
+
+func TestNonIntegerKey(t *testing.T) {
+	db, engine, err := basicSetup("TestNonIntegerKey.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&Document{}, &DocumentLabel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codec, err := KeyCodecOf[Document]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/document"), db, nil, &Crud[Document]{
+		EnableGetAll:   true,
+		OptimisticLock: true,
+		SearchHandlers: SearchHandlers{
+			"in_id": KeywordKeyIn("id", codec, nil),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetupM2MConnectorController[DocumentLabel](
+		engine.Group("/document-label"), db, nil,
+		"DocumentID", "Label",
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.key.NewAddress(0)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[Document](addr + "/document")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := crudy.Save(&Document{Title: "first"})
+	if err != nil {
+		t.Fatal(err)
+	} else if IsZeroKey(first.ID) {
+		t.Fatal("expected a generated id")
+	}
+	second, err := crudy.Save(&Document{Title: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	one, err := crudy.One(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if one.ID != first.ID || one.Title != "first" {
+		t.Fatalf("unexpected document %v", one)
+	}
+
+	patched, err := crudy.Patch(first.ID, map[string]any{"title": "patched", "updatedAt": one.UpdatedAt})
+	if err != nil {
+		t.Fatal(err)
+	} else if patched.Title != "patched" {
+		t.Fatalf("expected patched, got %s", patched.Title)
+	}
+
+	_, err = crudy.Save(one)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	} else if conflict.Details == nil || len(conflict.Details.IDs) != 1 || conflict.Details.IDs[0] != FormatKey(first.ID) {
+		t.Fatalf("expected stale id %s, got %v", FormatKey(first.ID), conflict.Details)
+	}
+
+	list, err := crudy.All(SearchParams{"in_id": FormatKey(second.ID)})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(list) != 1 || list[0].ID != second.ID {
+		t.Fatalf("expected the second one, got %v", list)
+	}
+
+	_, err = crudy.One("not-a-uuid")
+	if err == nil {
+		t.Fatal("expected error of invalid id")
+	}
+
+	deleted, err := crudy.DeleteMany(ToKeys(first.ID, second.ID))
+	if err != nil {
+		t.Fatal(err)
+	} else if !deleted {
+		t.Fatal("expected deleted")
+	}
+
+	restored, err := crudy.Restore(second.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if !restored {
+		t.Fatal("expected restored")
+	}
+
+	count, err := crudy.Count(SearchParams{})
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 2 {
+		t.Fatalf("expected 2 with the soft deleted one, got %d", count)
+	}
+
+	// m2m
+	handler, err := NewM2MConnectorHandler[Document, string, DocumentLabel](
+		addr+"/document-label", nil, nil,
+		"DocumentID", "Label",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := handler.Save([]DocumentLabel{
+		{DocumentID: second.ID, Label: "draft"},
+		{DocumentID: second.ID, Label: "public"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if saved != 2 {
+		t.Fatalf("expected 2 saved, got %d", saved)
+	}
+
+	labels, err := handler.GetAllKeys(ToKeys(second.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(labels) != 2 {
+		t.Fatalf("expected 2 labels, got %v", labels)
+	}
+
+	saved, err = handler.SaveAfterDelete("DocumentID", second.ID, []DocumentLabel{
+		{DocumentID: second.ID, Label: "final"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if saved != 1 {
+		t.Fatalf("expected 1 saved, got %d", saved)
+	}
+
+	removed, err := handler.Delete(second.ID, "final")
+	if err != nil {
+		t.Fatal(err)
+	} else if removed != 1 {
+		t.Fatalf("expected 1 deleted, got %d", removed)
+	}
+
+	labels, err = handler.GetAllKeys(nil, ToKeys("draft", "public", "final"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(labels) != 0 {
+		t.Fatalf("expected no label, got %v", labels)
+	}
+
+	res, err := fetchJSON[any](http.MethodDelete, addr+"/document-label?documentId=&label=final", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Code != RestCoder.BadRequest() {
+		t.Fatalf("expected bad request of empty key, got %s", res.Code)
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	db, engine, err := basicSetup("TestCompositeKey.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&Membership{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	primaryKey, err := PrimaryKeyOf[Membership](db)
+	if err != nil {
+		t.Fatal(err)
+	} else if !primaryKey.Composite() || !slices.Equal(primaryKey.Columns(), []string{"org_id", "code"}) {
+		t.Fatalf("unexpected primary columns %v", primaryKey.Columns())
+	}
+
+	keys := primaryKey.ParseMany("1, 2", "a,b")
+	if len(keys) != 2 || FormatKey(keys[1]) != "code=b&org_id=2" {
+		t.Fatalf("unexpected keys %v", keys)
+	} else if keys = primaryKey.ParseMany("1,2", "a"); keys != nil {
+		t.Fatalf("expected nil of different counts, got %v", keys)
+	}
+
+	audit, err := NewAudit(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/membership"), db, nil, &Crud[Membership]{
+		EnableGetAll:   true,
+		EnableCursor:   true,
+		OptimisticLock: true,
+		Audit:          audit,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.key.NewAddress(1)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[Membership](addr + "/membership")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, membership := range []Membership{
+		{OrgID: 1, Code: "a", Role: "admin"},
+		{OrgID: 1, Code: "b", Role: "member"},
+		{OrgID: 2, Code: "a", Role: "member"},
+	} {
+		_, err = crudy.Save(&membership)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a1 := CompositeKey{"org_id": ID(1), "code": "a"}
+	b1 := CompositeKey{"org_id": ID(1), "code": "b"}
+	a2 := CompositeKey{"org_id": ID(2), "code": "a"}
+
+	one, err := crudy.One(b1)
+	if err != nil {
+		t.Fatal(err)
+	} else if one.OrgID != 1 || one.Code != "b" || one.Role != "member" {
+		t.Fatalf("unexpected membership %v", one)
+	}
+
+	one, err = crudy.One(a1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patched, err := crudy.Patch(a1, map[string]any{"role": "owner", "updatedAt": one.UpdatedAt})
+	if err != nil {
+		t.Fatal(err)
+	} else if patched.Role != "owner" {
+		t.Fatalf("expected owner, got %s", patched.Role)
+	}
+
+	_, err = crudy.Save(one)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	} else if conflict.Details == nil || len(conflict.Details.IDs) != 1 || FormatKey(conflict.Details.IDs[0]) != FormatKey(a1) {
+		t.Fatalf("expected stale key %s, got %v", a1, conflict.Details)
+	}
+
+	res, err := fetchJSON[any](http.MethodGet, addr+"/membership/one/1", nil, nil)
+	if err == nil && res.Code == "0" {
+		t.Fatal("expected no route of a single key")
+	}
+
+	var iterated []Membership
+	for membership, err := range crudy.Iterate(2, nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		iterated = append(iterated, membership)
+	}
+	if len(iterated) != 3 {
+		t.Fatalf("expected 3 memberships, got %v", iterated)
+	}
+
+	deleted, err := crudy.DeleteMany([]Key{a1, a2})
+	if err != nil {
+		t.Fatal(err)
+	} else if !deleted {
+		t.Fatal("expected deleted")
+	}
+
+	list, err := crudy.All(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, membership := range list {
+		if (membership.Code == "a") != (membership.DeletedAt != nil) {
+			t.Fatalf("expected only memberships of code a deleted, got %v", list)
+		}
+	}
+
+	restored, err := crudy.Restore(a2)
+	if err != nil {
+		t.Fatal(err)
+	} else if !restored {
+		t.Fatal("expected restored")
+	}
+
+	_, err = crudy.Delete(ID(1))
+	if !errors.Is(err, InvalidKeyError) {
+		t.Fatalf("expected invalid key, got %v", err)
+	}
+
+	// the server would split it into two keys
+	_, err = crudy.One(CompositeKey{"org_id": ID(1), "code": "a,b"})
+	if !errors.Is(err, InvalidKeyError) {
+		t.Fatalf("expected invalid key of a comma, got %v", err)
+	}
+
+	logs, err := fetchJSON[[]AuditLog](http.MethodGet, addr+"/membership/audit/1/a", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs.Data) != 3 || logs.Data[0].Operation != AuditOperationDelete || logs.Data[0].RecordID != FormatKey(a1) {
+		t.Fatalf("expected save, save and delete of %s, got %v", a1, logs.Data)
+	}
+
+	// the stored record is found by the composite key
+	checkRole, err := NewDuplicateFieldCheckFunc[Membership](db, gogger.New("membership"), "Role")
+	if err != nil {
+		t.Fatal(err)
+	}
+	context, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if err = checkRole(context, &Membership{OrgID: 1, Code: "b", Role: "member"}); err != nil {
+		t.Fatalf("expected unchanged role to pass, got %v", err)
+	}
+	if err = checkRole(context, &Membership{OrgID: 1, Code: "c", Role: "member"}); err == nil {
+		t.Fatal("expected role member to be taken")
+	}
+}
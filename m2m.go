@@ -2,6 +2,7 @@ package gocrud
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,7 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
-	"strconv"
+	"slices"
 	"strings"
 
 	"github.com/allape/gogger"
@@ -31,6 +32,28 @@ type SetupM2MConnectorControllerOptions[T any] struct {
 	OnDelete func(db *gorm.DB, context *gin.Context)
 
 	ExtraSearchHandlers SearchHandlers
+
+	// Audit
+	// logs saves and deletes, record id of AuditLog is `id1,id2`
+	Audit *Audit
+
+	// Tenancy
+	// filters reads and deletes by the tenant of the request, stamps it on saved records,
+	// and denies saving records those belong to other tenants
+	Tenancy *Tenancy
+
+	// OpenAPI
+	// documents all routes of this controller
+	OpenAPI *OpenAPI
+
+	// Validator
+	// validates saved records with struct tags of ValidationTags, DefaultValidator is used if it is nil,
+	// failures are responded with FieldErrors as data, such as `[0].userId`
+	Validator *Validator
+
+	// Invalidates
+	// invalidated after saves and deletes, such as Cruds of models those preload this connector
+	Invalidates []CacheInvalidator
 }
 
 // SetupM2MConnectorController
@@ -50,27 +73,40 @@ func SetupM2MConnectorController[T any](
 	if options.OnRecordCheck == nil {
 		options.OnRecordCheck = func(record *T, db *gorm.DB, context *gin.Context) {}
 	}
+	if options.Validator == nil {
+		options.Validator = DefaultValidator
+	}
 
 	var jsonFieldName1, jsonFieldName2 string
 	var databaseFieldName1, databaseFieldName2 string
+	var keyCodec1, keyCodec2 KeyCodec
+	var keyType1, keyType2 reflect.Type
 
 	// setup check
 	// use a block to drop them after check is done
 	{
 		reflected := reflect.TypeFor[T]()
 
+		var err error
+
 		ofn1, ok := reflected.FieldByName(objectFieldName1)
 		if !ok {
 			return fmt.Errorf("field %s is invalid", objectFieldName1)
-		} else if ofn1.Type.Kind() != IDKind {
-			return fmt.Errorf("type of field %s is invalid, should be %d", objectFieldName1, IDKind)
+		}
+		keyType1 = ofn1.Type
+		keyCodec1, err = KeyCodecFor(ofn1.Type)
+		if err != nil {
+			return fmt.Errorf("type of field %s is invalid: %v", objectFieldName1, err)
 		}
 
 		ofn2, ok := reflected.FieldByName(objectFieldName2)
 		if !ok {
 			return fmt.Errorf("field %s is invalid", objectFieldName2)
-		} else if ofn2.Type.Kind() != IDKind {
-			return fmt.Errorf("type of field %s is invalid, should be %d", objectFieldName2, IDKind)
+		}
+		keyType2 = ofn2.Type
+		keyCodec2, err = KeyCodecFor(ofn2.Type)
+		if err != nil {
+			return fmt.Errorf("type of field %s is invalid: %v", objectFieldName2, err)
 		}
 
 		jsonFields, err := GetJSONFieldNameOf[T](objectFieldName1, objectFieldName2)
@@ -90,12 +126,170 @@ func SetupM2MConnectorController[T any](
 		databaseFieldName2 = databaseFields[1]
 	}
 
+	var tenantColumn string
+	if options.Tenancy != nil {
+		var err error
+		tenantColumn, err = tenantColumnOf[T](db, options.Tenancy)
+		if err != nil {
+			return err
+		}
+	}
+
+	// tenantOf
+	// responds Forbidden if the tenant can not be resolved, the tenant is nil without Tenancy
+	tenantOf := func(context *gin.Context) (any, bool) {
+		if options.Tenancy == nil {
+			return nil, true
+		}
+		tenant, err := options.Tenancy.Tenant(context)
+		if err != nil {
+			MakeErrorResponse(context, RestCoder.Forbidden(), err)
+			return nil, false
+		}
+		return tenant, true
+	}
+
+	scoped := func(repo *gorm.DB, tenant any) *gorm.DB {
+		if options.Tenancy == nil {
+			return repo
+		}
+		return options.Tenancy.Scope(repo, tenantColumn, tenant)
+	}
+
+	// stamp
+	// sets the tenant to every record
+	stamp := func(records []T, tenant any) error {
+		if options.Tenancy == nil {
+			return nil
+		}
+		for i := range records {
+			if err := options.Tenancy.Stamp(&records[i], tenant); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// invalidateCache
+	// connections of models those preload this connector may have been changed
+	invalidateCache := func() {
+		for _, invalidator := range options.Invalidates {
+			invalidator.InvalidateCache()
+		}
+	}
+
+	// checkForeign
+	// returns CrossTenantError if any of records has been saved by another tenant
+	checkForeign := func(tx *gorm.DB, tenant any, records []T) error {
+		if options.Tenancy == nil {
+			return nil
+		}
+		for i := range records {
+			reflected := reflect.ValueOf(records[i])
+
+			var count int64
+			err := options.Tenancy.Foreign(tx.Model(new(T)), tenantColumn, tenant).
+				Where(
+					fmt.Sprintf("`%s` = ? AND `%s` = ?", databaseFieldName1, databaseFieldName2),
+					reflected.FieldByName(objectFieldName1).Interface(),
+					reflected.FieldByName(objectFieldName2).Interface(),
+				).
+				Count(&count).Error
+			if err != nil {
+				return err
+			} else if count > 0 {
+				return CrossTenantError
+			}
+		}
+		return nil
+	}
+
+	// validate
+	// responds FieldErrors of all records
+	validate := func(context *gin.Context, records []T) bool {
+		var fieldErrors FieldErrors
+		for i := range records {
+			err := options.Validator.Validate(&records[i])
+			var errs FieldErrors
+			if errors.As(err, &errs) {
+				fieldErrors = append(fieldErrors, errs.WithPrefix(fmt.Sprintf("[%d]", i))...)
+			} else if err != nil {
+				logger.Error().Printf("failed to validate record: %v", err)
+				MakeErrorResponse(context, RestCoder.InternalServerError(), "[error] failed to validate")
+				return false
+			}
+		}
+		if len(fieldErrors) > 0 {
+			MakeErrorDataResponse(context, RestCoder.BadRequest(), fieldErrors, fieldErrors)
+			return false
+		}
+		return true
+	}
+
+	modelName := reflect.TypeFor[T]().Name()
+
+	auditRecordIDOf := func(record *T) string {
+		reflected := reflect.ValueOf(record).Elem()
+		return fmt.Sprintf(
+			"%s,%s",
+			FormatKey(reflected.FieldByName(objectFieldName1).Interface()),
+			FormatKey(reflected.FieldByName(objectFieldName2).Interface()),
+		)
+	}
+
+	// auditRecordsOf
+	// records found by where, keyed by audit record id
+	auditRecordsOf := func(tx *gorm.DB, where string, args ...any) (map[string]*T, error) {
+		if options.Audit == nil {
+			return nil, nil
+		}
+
+		var list []T
+		if err := tx.Model(new(T)).Where(where, args...).Find(&list).Error; err != nil {
+			return nil, err
+		}
+
+		records := make(map[string]*T, len(list))
+		for i := range list {
+			records[auditRecordIDOf(&list[i])] = &list[i]
+		}
+		return records, nil
+	}
+
+	// auditSave
+	// logs records those are not in records as deleted
+	auditSave := func(tx *gorm.DB, context *gin.Context, before map[string]*T, records []T) error {
+		if options.Audit == nil {
+			return nil
+		}
+
+		saved := make(map[string]bool, len(records))
+		for i := range records {
+			recordID := auditRecordIDOf(&records[i])
+			saved[recordID] = true
+			if err := options.Audit.Log(tx, context, AuditOperationSave, modelName, recordID, before[recordID], &records[i]); err != nil {
+				return err
+			}
+		}
+
+		for recordID, record := range before {
+			if saved[recordID] {
+				continue
+			}
+			if err := options.Audit.Log(tx, context, AuditOperationDelete, modelName, recordID, record, nil); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
 	inFieldName1 := "in_" + jsonFieldName1
 	inFieldName2 := "in_" + jsonFieldName2
 
-	var handleKeywordIdIn = func(databaseFieldName string) SearchHandler {
+	var handleKeywordIdIn = func(databaseFieldName string, keyCodec KeyCodec) SearchHandler {
 		return func(db *gorm.DB, values []string, context *gin.Context) (*gorm.DB, error) {
-			return KeywordIDIn(databaseFieldName, func(value []ID) []ID {
+			return KeywordKeyIn(databaseFieldName, keyCodec, func(value []Key) []Key {
 				if len(value) > 0 {
 					context.Set(ContextKeyHandledKeywordIn, true)
 				}
@@ -106,16 +300,23 @@ func SetupM2MConnectorController[T any](
 
 	searchHandlers := MergeSearchHandlers(
 		SearchHandlers{
-			inFieldName1: handleKeywordIdIn(databaseFieldName1),
-			inFieldName2: handleKeywordIdIn(databaseFieldName2),
+			inFieldName1: handleKeywordIdIn(databaseFieldName1, keyCodec1),
+			inFieldName2: handleKeywordIdIn(databaseFieldName2, keyCodec2),
 		},
 		options.ExtraSearchHandlers,
 	)
 
 	var getAllHandler gin.HandlerFunc = func(context *gin.Context) {
+		db := WithRequestContext(db, context)
+
 		var err error
 
-		repo := db.Model(new(T))
+		tenant, ok := tenantOf(context)
+		if !ok {
+			return
+		}
+
+		repo := scoped(db.Model(new(T)), tenant)
 
 		repo, err = HandleSearch(context, repo, searchHandlers)
 		if err != nil {
@@ -123,7 +324,7 @@ func SetupM2MConnectorController[T any](
 			return
 		}
 
-		_, ok := context.Get(ContextKeyHandledKeywordIn)
+		_, ok = context.Get(ContextKeyHandledKeywordIn)
 		if !ok {
 			MakeErrorResponse(context, RestCoder.BadRequest(), fmt.Sprintf("at least one of %s or %s should not be empty", inFieldName1, inFieldName2))
 			return
@@ -143,24 +344,33 @@ func SetupM2MConnectorController[T any](
 	group.POST("/all", getAllHandler)
 
 	group.PUT("/save", func(context *gin.Context) {
+		db := WithRequestContext(db, context)
+
 		var records []T
-		if err := context.ShouldBindJSON(&records); err != nil {
+		if err := BindJSON(context, &records); err != nil {
 			MakeErrorResponse(context, RestCoder.BadRequest(), "[error] failed to parse body")
 			return
 		}
 
+		tenant, ok := tenantOf(context)
+		if !ok {
+			return
+		}
+		if err := stamp(records, tenant); err != nil {
+			MakeErrorResponse(context, RestCoder.BadRequest(), err)
+			return
+		}
+
 		for index, record := range records {
 			reflected := reflect.ValueOf(record)
 
-			id1 := reflected.FieldByName(objectFieldName1).Uint()
-			if id1 == 0 {
-				MakeErrorResponse(context, RestCoder.BadRequest(), fmt.Sprintf("%s can not be 0 at %d", jsonFieldName1, index))
+			if reflected.FieldByName(objectFieldName1).IsZero() {
+				MakeErrorResponse(context, RestCoder.BadRequest(), fmt.Sprintf("%s can not be empty at %d", jsonFieldName1, index))
 				return
 			}
 
-			id2 := reflected.FieldByName(objectFieldName2).Uint()
-			if id2 == 0 {
-				MakeErrorResponse(context, RestCoder.BadRequest(), fmt.Sprintf("%s can not be 0 at %d", jsonFieldName2, index))
+			if reflected.FieldByName(objectFieldName2).IsZero() {
+				MakeErrorResponse(context, RestCoder.BadRequest(), fmt.Sprintf("%s can not be empty at %d", jsonFieldName2, index))
 				return
 			}
 
@@ -170,38 +380,88 @@ func SetupM2MConnectorController[T any](
 			}
 		}
 
-		res := db.Save(&records)
+		if !validate(context, records) {
+			return
+		}
+
+		count := int64(0)
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := checkForeign(tx, tenant, records); err != nil {
+				return err
+			}
 
-		if err := res.Error; err != nil {
+			before := make(map[string]*T)
+			for i := range records {
+				stored, err := auditRecordsOf(
+					tx, fmt.Sprintf("`%s` = ? AND `%s` = ?", databaseFieldName1, databaseFieldName2),
+					reflect.ValueOf(records[i]).FieldByName(objectFieldName1).Interface(),
+					reflect.ValueOf(records[i]).FieldByName(objectFieldName2).Interface(),
+				)
+				if err != nil {
+					return err
+				}
+				maps.Insert(before, maps.All(stored))
+			}
+
+			res := tx.Save(&records)
+			if res.Error != nil {
+				return res.Error
+			}
+
+			count = res.RowsAffected
+
+			return auditSave(tx, context, before, records)
+		})
+		if errors.Is(err, CrossTenantError) {
+			MakeErrorResponse(context, RestCoder.Forbidden(), err)
+			return
+		} else if err != nil {
 			logger.Error().Printf("failed to save record: %v", err)
+			MakeErrorResponse(context, RestCoder.InternalServerError(), "[error] failed to save")
 			return
 		}
 
-		MakeOkayDataResponse(context, res.RowsAffected)
+		invalidateCache()
+
+		MakeOkayDataResponse(context, count)
 	})
 
 	group.POST("/save/:deleteByField/:deleteById", func(context *gin.Context) {
+		db := WithRequestContext(db, context)
+
 		deleteByField := strings.TrimSpace(context.Param("deleteByField"))
 		if deleteByField != jsonFieldName1 && deleteByField != jsonFieldName2 {
 			MakeErrorResponse(context, RestCoder.BadRequest(), "field for delete is invalid")
 			return
 		}
 
-		deleteById, err := strconv.ParseUint(context.Param("deleteById"), 10, 64)
+		keyCodec := Ternary(deleteByField == jsonFieldName1, keyCodec1, keyCodec2)
+
+		deleteById, err := keyCodec.Parse(context.Param("deleteById"))
 		if err != nil {
 			MakeErrorResponse(context, RestCoder.BadRequest(), "id for delete is invalid")
 			return
-		} else if deleteById == 0 {
-			MakeErrorResponse(context, RestCoder.BadRequest(), "id for delete can not be 0")
+		} else if IsZeroKey(deleteById) {
+			MakeErrorResponse(context, RestCoder.BadRequest(), "id for delete can not be empty")
 			return
 		}
 
 		var records []T
-		if err := context.ShouldBindJSON(&records); err != nil {
+		if err := BindJSON(context, &records); err != nil {
 			MakeErrorResponse(context, RestCoder.BadRequest(), "invalid request body")
 			return
 		}
 
+		tenant, ok := tenantOf(context)
+		if !ok {
+			return
+		}
+		if err := stamp(records, tenant); err != nil {
+			MakeErrorResponse(context, RestCoder.BadRequest(), err)
+			return
+		}
+
 		var objectPrimaryFieldName string
 		var dbFieldName string
 
@@ -217,9 +477,9 @@ func SetupM2MConnectorController[T any](
 		for i, record := range records {
 			reflected := reflect.ValueOf(record)
 			idField := reflected.FieldByName(objectPrimaryFieldName)
-			id := idField.Uint()
+			id := idField.Interface()
 			if id != deleteById {
-				MakeErrorResponse(context, RestCoder.BadRequest(), fmt.Sprintf("id of record at %d is invalid, expect %d, but got %d", i, deleteById, id))
+				MakeErrorResponse(context, RestCoder.BadRequest(), fmt.Sprintf("id of record at %d is invalid, expect %s, but got %s", i, FormatKey(deleteById), FormatKey(id)))
 				return
 			}
 
@@ -229,63 +489,140 @@ func SetupM2MConnectorController[T any](
 			}
 		}
 
+		if !validate(context, records) {
+			return
+		}
+
 		count := int64(0)
 
 		err = db.Transaction(func(tx *gorm.DB) error {
-			if err := tx.Delete(new(T), fmt.Sprintf("`%s` = ?", dbFieldName), deleteById).Error; err != nil {
+			if err := checkForeign(tx, tenant, records); err != nil {
 				return err
 			}
 
-			if len(records) == 0 {
-				return nil
+			before, err := auditRecordsOf(scoped(tx, tenant), fmt.Sprintf("`%s` = ?", dbFieldName), deleteById)
+			if err != nil {
+				return err
 			}
 
-			res := tx.Save(records)
-			if res.Error != nil {
-				return res.Error
+			if err := scoped(tx, tenant).Delete(new(T), fmt.Sprintf("`%s` = ?", dbFieldName), deleteById).Error; err != nil {
+				return err
 			}
 
-			count = res.RowsAffected
+			if len(records) > 0 {
+				res := tx.Save(records)
+				if res.Error != nil {
+					return res.Error
+				}
 
-			return nil
+				count = res.RowsAffected
+			}
+
+			return auditSave(tx, context, before, records)
 		})
-		if err != nil {
-			logger.Error().Printf("failed to save %v for %s of %d: %v", records, deleteByField, deleteById, err)
+		if errors.Is(err, CrossTenantError) {
+			MakeErrorResponse(context, RestCoder.Forbidden(), err)
+			return
+		} else if err != nil {
+			logger.Error().Printf("failed to save %v for %s of %s: %v", records, deleteByField, FormatKey(deleteById), err)
 			MakeErrorResponse(context, RestCoder.InternalServerError(), "[error] failed to save")
 			return
 		}
 
+		invalidateCache()
+
 		MakeOkayDataResponse(context, count)
 	})
 
 	// ?[jsonFieldName1]=id1&[jsonFieldName2]=id2
 	group.DELETE("", func(context *gin.Context) {
+		db := WithRequestContext(db, context)
+
+		tenant, ok := tenantOf(context)
+		if !ok {
+			return
+		}
+
 		if options.OnDelete != nil {
-			options.OnDelete(db, context)
+			options.OnDelete(scoped(db, tenant), context)
+			invalidateCache()
 			return
 		}
 
-		id1, err := strconv.ParseUint(context.Query(jsonFieldName1), 10, 64)
+		id1, err := keyCodec1.Parse(context.Query(jsonFieldName1))
 		if err != nil {
 			MakeErrorResponse(context, RestCoder.BadRequest(), fmt.Sprintf("value of %s is invalid", jsonFieldName1))
 			return
 		}
-		id2, err := strconv.ParseUint(context.Query(jsonFieldName2), 10, 64)
+		id2, err := keyCodec2.Parse(context.Query(jsonFieldName2))
 		if err != nil {
 			MakeErrorResponse(context, RestCoder.BadRequest(), fmt.Sprintf("value of %s is invalid", jsonFieldName2))
 			return
 		}
 
-		res := db.Delete(new(T), fmt.Sprintf("`%s` = ? AND `%s` = ?", databaseFieldName1, databaseFieldName2), id1, id2)
-		if res.Error != nil {
-			logger.Error().Printf("failed to delete at %d,%d: %v", id1, id2, res.Error)
+		where := fmt.Sprintf("`%s` = ? AND `%s` = ?", databaseFieldName1, databaseFieldName2)
+		count := int64(0)
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			before, err := auditRecordsOf(scoped(tx, tenant), where, id1, id2)
+			if err != nil {
+				return err
+			}
+
+			res := scoped(tx, tenant).Delete(new(T), where, id1, id2)
+			if res.Error != nil {
+				return res.Error
+			}
+
+			count = res.RowsAffected
+
+			return auditSave(tx, context, before, nil)
+		})
+		if err != nil {
+			logger.Error().Printf("failed to delete at %s,%s: %v", FormatKey(id1), FormatKey(id2), err)
 			MakeErrorResponse(context, RestCoder.InternalServerError(), "[error] failed to delete")
 			return
 		}
 
-		MakeOkayDataResponse(context, res.RowsAffected)
+		invalidateCache()
+
+		MakeOkayDataResponse(context, count)
 	})
 
+	if options.OpenAPI != nil {
+		tag := openAPITagOf(group)
+		searchKeys := slices.Sorted(maps.Keys(searchHandlers))
+
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			options.OpenAPI.Add(OpenAPIRoute{
+				Method: method, Path: openAPIPathOf(group, "/all"), Tag: tag, Summary: "all",
+				SearchKeys: searchKeys,
+				Data:       reflect.TypeFor[[]T](),
+			})
+		}
+		options.OpenAPI.Add(OpenAPIRoute{
+			Method: http.MethodPut, Path: openAPIPathOf(group, "/save"), Tag: tag, Summary: "save",
+			Body: reflect.TypeFor[[]T](),
+			Data: reflect.TypeFor[int64](),
+		})
+		// deleteById is a key of either field
+		var keyTypes map[string]reflect.Type
+		if keyType1 == keyType2 {
+			keyTypes = map[string]reflect.Type{"deleteById": keyType1}
+		}
+		options.OpenAPI.Add(OpenAPIRoute{
+			Method: http.MethodPost, Path: openAPIPathOf(group, "/save/:deleteByField/:deleteById"), Tag: tag, Summary: "save after delete",
+			KeyTypes: keyTypes,
+			Body:     reflect.TypeFor[[]T](),
+			Data:     reflect.TypeFor[int64](),
+		})
+		options.OpenAPI.Add(OpenAPIRoute{
+			Method: http.MethodDelete, Path: openAPIPathOf(group, ""), Tag: tag, Summary: "delete",
+			QueryKeys: []string{jsonFieldName1, jsonFieldName2},
+			Data:      reflect.TypeFor[int64](),
+		})
+	}
+
 	return nil
 }
 
@@ -306,6 +643,24 @@ type M2MConnectorHandler[M1 any, M2 any, M2MConnector any] struct {
 }
 
 func (d *M2MConnectorHandler[M1, M2, M2MConnector]) GetAll(t1IDs, t2IDs []ID, params ...SearchParams) ([]M2MConnector, error) {
+	return d.GetAllContext(context.Background(), t1IDs, t2IDs, params...)
+}
+
+// GetAllContext
+// same as GetAll, the request is canceled with ctx
+func (d *M2MConnectorHandler[M1, M2, M2MConnector]) GetAllContext(ctx context.Context, t1IDs, t2IDs []ID, params ...SearchParams) ([]M2MConnector, error) {
+	return d.GetAllKeysContext(ctx, ToKeys(t1IDs...), ToKeys(t2IDs...), params...)
+}
+
+// GetAllKeys
+// same as GetAll, for connectors of keys those are not ID, such as strings
+func (d *M2MConnectorHandler[M1, M2, M2MConnector]) GetAllKeys(t1IDs, t2IDs []Key, params ...SearchParams) ([]M2MConnector, error) {
+	return d.GetAllKeysContext(context.Background(), t1IDs, t2IDs, params...)
+}
+
+// GetAllKeysContext
+// same as GetAllKeys, the request is canceled with ctx
+func (d *M2MConnectorHandler[M1, M2, M2MConnector]) GetAllKeysContext(ctx context.Context, t1IDs, t2IDs []Key, params ...SearchParams) ([]M2MConnector, error) {
 	if len(t1IDs) == 0 && len(t2IDs) == 0 {
 		return nil, errors.New("t1IDs and t2IDs can not be empty at the same time")
 	}
@@ -316,10 +671,10 @@ func (d *M2MConnectorHandler[M1, M2, M2MConnector]) GetAll(t1IDs, t2IDs []ID, pa
 	}
 
 	if len(t1IDs) > 0 {
-		mergedParams["in_"+d.jsonFieldName1] = IDsJoin(t1IDs, ",")
+		mergedParams["in_"+d.jsonFieldName1] = KeysJoin(t1IDs, ",")
 	}
 	if len(t2IDs) > 0 {
-		mergedParams["in_"+d.jsonFieldName2] = IDsJoin(t2IDs, ",")
+		mergedParams["in_"+d.jsonFieldName2] = KeysJoin(t2IDs, ",")
 	}
 
 	u, err := url.Parse(d.baseURL + "/all")
@@ -334,7 +689,7 @@ func (d *M2MConnectorHandler[M1, M2, M2MConnector]) GetAll(t1IDs, t2IDs []ID, pa
 
 	res := new(R[[]M2MConnector])
 
-	err = MakeJSONRequest[[]M2MConnector](d.httpClient, d.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), res)
+	err = MakeJSONRequestContext[[]M2MConnector](ctx, d.httpClient, d.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), res)
 	if err != nil {
 		return nil, err
 	} else if res == nil {
@@ -345,6 +700,12 @@ func (d *M2MConnectorHandler[M1, M2, M2MConnector]) GetAll(t1IDs, t2IDs []ID, pa
 }
 
 func (d *M2MConnectorHandler[M1, M2, M2MConnector]) Save(records []M2MConnector) (int64, error) {
+	return d.SaveContext(context.Background(), records)
+}
+
+// SaveContext
+// same as Save, the request is canceled with ctx
+func (d *M2MConnectorHandler[M1, M2, M2MConnector]) SaveContext(ctx context.Context, records []M2MConnector) (int64, error) {
 	u, err := url.Parse(d.baseURL + "/save")
 	if err != nil {
 		return -1, err
@@ -356,7 +717,7 @@ func (d *M2MConnectorHandler[M1, M2, M2MConnector]) Save(records []M2MConnector)
 	}
 
 	res := new(R[int64])
-	err = MakeJSONRequest(d.httpClient, d.okayHttpStatusRange, u, http.MethodPut, bytes.NewReader(body), res)
+	err = MakeJSONRequestContext(ctx, d.httpClient, d.okayHttpStatusRange, u, http.MethodPut, bytes.NewReader(body), res)
 	if err != nil {
 		return -1, err
 	}
@@ -364,7 +725,13 @@ func (d *M2MConnectorHandler[M1, M2, M2MConnector]) Save(records []M2MConnector)
 	return res.Data, nil
 }
 
-func (d *M2MConnectorHandler[M1, M2, M2MConnector]) SaveAfterDelete(deleteByField string, idToDelete ID, records []M2MConnector) (int64, error) {
+func (d *M2MConnectorHandler[M1, M2, M2MConnector]) SaveAfterDelete(deleteByField string, idToDelete Key, records []M2MConnector) (int64, error) {
+	return d.SaveAfterDeleteContext(context.Background(), deleteByField, idToDelete, records)
+}
+
+// SaveAfterDeleteContext
+// same as SaveAfterDelete, the request is canceled with ctx
+func (d *M2MConnectorHandler[M1, M2, M2MConnector]) SaveAfterDeleteContext(ctx context.Context, deleteByField string, idToDelete Key, records []M2MConnector) (int64, error) {
 	if deleteByField != d.ObjectFieldName1 && deleteByField != d.ObjectFieldName2 {
 		return -1, fmt.Errorf("deleteByField must be %s or %s", d.ObjectFieldName1, d.ObjectFieldName2)
 	}
@@ -377,7 +744,7 @@ func (d *M2MConnectorHandler[M1, M2, M2MConnector]) SaveAfterDelete(deleteByFiel
 		jsonField = d.jsonFieldName2
 	}
 
-	u, err := url.Parse(fmt.Sprintf("%s/save/%s/%d", d.baseURL, jsonField, idToDelete))
+	u, err := url.Parse(fmt.Sprintf("%s/save/%s/%s", d.baseURL, jsonField, url.PathEscape(FormatKey(idToDelete))))
 	if err != nil {
 		return -1, err
 	}
@@ -386,8 +753,8 @@ func (d *M2MConnectorHandler[M1, M2, M2MConnector]) SaveAfterDelete(deleteByFiel
 		reflected := reflect.ValueOf(record)
 		idField := reflected.FieldByName(deleteByField)
 
-		if idField.Uint() != uint64(idToDelete) {
-			return -1, fmt.Errorf("%s must be %d at index of %d", deleteByField, idToDelete, index)
+		if FormatKey(idField.Interface()) != FormatKey(idToDelete) {
+			return -1, fmt.Errorf("%s must be %s at index of %d", deleteByField, FormatKey(idToDelete), index)
 		}
 	}
 
@@ -398,7 +765,7 @@ func (d *M2MConnectorHandler[M1, M2, M2MConnector]) SaveAfterDelete(deleteByFiel
 
 	res := new(R[int64])
 
-	err = MakeJSONRequest(d.httpClient, d.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), res)
+	err = MakeJSONRequestContext(ctx, d.httpClient, d.okayHttpStatusRange, u, http.MethodPost, bytes.NewReader(body), res)
 	if err != nil {
 		return -1, err
 	} else if res == nil {
@@ -408,14 +775,25 @@ func (d *M2MConnectorHandler[M1, M2, M2MConnector]) SaveAfterDelete(deleteByFiel
 	return res.Data, nil
 }
 
-func (d *M2MConnectorHandler[M1, M2, M2MConnector]) Delete(id1, id2 ID) (int64, error) {
-	u, err := url.Parse(fmt.Sprintf("%s?%s=%d&%s=%d", d.baseURL, url.QueryEscape(d.jsonFieldName1), id1, url.QueryEscape(d.jsonFieldName2), id2))
+func (d *M2MConnectorHandler[M1, M2, M2MConnector]) Delete(id1, id2 Key) (int64, error) {
+	return d.DeleteContext(context.Background(), id1, id2)
+}
+
+// DeleteContext
+// same as Delete, the request is canceled with ctx
+func (d *M2MConnectorHandler[M1, M2, M2MConnector]) DeleteContext(ctx context.Context, id1, id2 Key) (int64, error) {
+	u, err := url.Parse(fmt.Sprintf(
+		"%s?%s=%s&%s=%s",
+		d.baseURL,
+		url.QueryEscape(d.jsonFieldName1), url.QueryEscape(FormatKey(id1)),
+		url.QueryEscape(d.jsonFieldName2), url.QueryEscape(FormatKey(id2)),
+	))
 	if err != nil {
 		return -1, err
 	}
 
 	res := new(R[int64])
-	err = MakeJSONRequest(d.httpClient, d.okayHttpStatusRange, u, http.MethodDelete, bytes.NewReader(nil), res)
+	err = MakeJSONRequestContext(ctx, d.httpClient, d.okayHttpStatusRange, u, http.MethodDelete, bytes.NewReader(nil), res)
 	if err != nil {
 		return -1, err
 	} else if res == nil {
@@ -444,15 +822,15 @@ func NewM2MConnectorHandler[M1 any, M2 any, M2MConnector any](
 		field1, ok := reflected.FieldByName(objectFieldName1)
 		if !ok {
 			return nil, fmt.Errorf("field %s does NOT exist in %s", objectFieldName1, reflected.Name())
-		} else if field1.Type.Kind() != IDKind {
-			return nil, fmt.Errorf("field %s type MUST be %s, but got %s", objectFieldName1, IDKind, field1.Type.Kind())
+		} else if _, err := KeyCodecFor(field1.Type); err != nil {
+			return nil, fmt.Errorf("field %s type is invalid: %v", objectFieldName1, err)
 		}
 
 		field2, ok := reflected.FieldByName(objectFieldName2)
 		if !ok {
 			return nil, fmt.Errorf("field %s does NOT exist in %s", objectFieldName2, reflected.Name())
-		} else if field2.Type.Kind() != IDKind {
-			return nil, fmt.Errorf("field %s type MUST be %s, but got %s", objectFieldName2, IDKind, field2.Type.Kind())
+		} else if _, err := KeyCodecFor(field2.Type); err != nil {
+			return nil, fmt.Errorf("field %s type is invalid: %v", objectFieldName2, err)
 		}
 	}
 
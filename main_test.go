@@ -14,27 +14,45 @@ func (b baseAddress) NewAddress(portOffset int64) string {
 }
 
 type testAddress struct {
+	audit         baseAddress
+	cache         baseAddress
 	crud          baseAddress
+	crudExtra     baseAddress
 	crudy         baseAddress
+	events        baseAddress
 	fsDare        baseAddress
 	fsObject      baseAddress
 	fs            baseAddress
 	helper        baseAddress
 	index         baseAddress
+	key           baseAddress
 	m2m           baseAddress
 	model         baseAddress
+	openAPI       baseAddress
 	searchHandler baseAddress
+	tenancy       baseAddress
+	validation    baseAddress
+	webhook       baseAddress
 }
 
 var address = testAddress{
+	audit:         baseAddress{"127.0.0.1", 8110},
+	cache:         baseAddress{"127.0.0.1", 8150},
 	crud:          baseAddress{"127.0.0.1", 8080},
+	crudExtra:     baseAddress{"127.0.0.1", 8100},
 	crudy:         baseAddress{"127.0.0.1", 8000},
+	events:        baseAddress{"127.0.0.1", 8180},
 	fsDare:        baseAddress{"127.0.0.1", 8010},
 	fsObject:      baseAddress{"127.0.0.1", 8020},
 	fs:            baseAddress{"127.0.0.1", 8030},
 	helper:        baseAddress{"127.0.0.1", 8040},
 	index:         baseAddress{"127.0.0.1", 8050},
+	key:           baseAddress{"127.0.0.1", 8170},
 	m2m:           baseAddress{"127.0.0.1", 8060},
 	model:         baseAddress{"127.0.0.1", 8070},
+	openAPI:       baseAddress{"127.0.0.1", 8130},
 	searchHandler: baseAddress{"127.0.0.1", 8090},
+	tenancy:       baseAddress{"127.0.0.1", 8120},
+	validation:    baseAddress{"127.0.0.1", 8140},
+	webhook:       baseAddress{"127.0.0.1", 8160},
 }
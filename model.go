@@ -14,6 +14,8 @@ import (
 
 type ID uint64
 
+// IDKind
+// kind of ID, keys of other types are supported by KeyCodec
 var IDKind = reflect.Uint64
 
 type Base struct {
@@ -48,37 +50,84 @@ func BaseSearchHandlers(overrideSearchHandlers ...SearchHandlers) SearchHandlers
 	return MergeSearchHandlers(base, overrideSearchHandlers...)
 }
 
+// keysOfContext
+// keys set by Crud with SetKeys, or comma separated keys in param parsed by KeyCodecOf T
+func keysOfContext[T any](context *gin.Context, param string) []Key {
+	if keys, ok := GetKeys(context); ok {
+		return keys
+	}
+	return KeysOf[T](context.Param(param))
+}
+
+// whereKeysOf
+// filters db by keys with PrimaryKeyOf T
+func whereKeysOf[T any](db *gorm.DB, keys []Key) *gorm.DB {
+	primaryKey, err := PrimaryKeyOf[T](db)
+	if err != nil {
+		return withError(db, err)
+	}
+	return primaryKey.Where(db, keys)
+}
+
+// NewHardDeleteHandler
+// `:id` accepts comma separated keys parsed by KeyCodecOf T, such as `1,2,3`, see Crud for composite keys
 func NewHardDeleteHandler[T any](coder Coder) func(context *gin.Context, db *gorm.DB) bool {
 	var record T
 	return func(context *gin.Context, db *gorm.DB) bool {
-		id := context.Param("id")
-		if id == "" {
+		ids := keysOfContext[T](context, "id")
+		if len(ids) == 0 {
 			MakeErrorResponse(context, coder.BadRequest(), "invalid id")
 			return false
 		}
 
-		res := db.Delete(&record, id)
+		res := whereKeysOf[T](db, ids).Delete(&record)
 
 		return res.RowsAffected > 0
 	}
 }
 
+// NewSoftDeleteHandler
+// `:id` accepts comma separated keys parsed by KeyCodecOf T, such as `1,2,3`, see Crud for composite keys
 func NewSoftDeleteHandler[T any](coder Coder) func(context *gin.Context, db *gorm.DB) bool {
 	var record T
 	return func(context *gin.Context, db *gorm.DB) bool {
-		id := context.Param("id")
-
-		if id == "" {
+		ids := keysOfContext[T](context, "id")
+		if len(ids) == 0 {
 			MakeErrorResponse(context, coder.BadRequest(), "invalid id")
 			return false
 		}
 
-		res := db.Model(&record).Where("id = ?", id).UpdateColumn("deleted_at", time.Now())
+		res := whereKeysOf[T](db.Model(&record), ids).UpdateColumn("deleted_at", time.Now())
 
 		return res.RowsAffected > 0
 	}
 }
 
+// NewRestoreHandler
+// clears `deleted_at` of soft deleted records, `:ids` accepts comma separated keys parsed by KeyCodecOf T, such as `1,2,3`,
+// see Crud for composite keys
+func NewRestoreHandler[T any](coder Coder) func(context *gin.Context, db *gorm.DB) bool {
+	var record T
+	return func(context *gin.Context, db *gorm.DB) bool {
+		ids := keysOfContext[T](context, "ids")
+		if len(ids) == 0 {
+			MakeErrorResponse(context, coder.BadRequest(), "invalid ids")
+			return false
+		}
+
+		res := whereKeysOf[T](db.Model(&record), ids).Where("deleted_at IS NOT NULL").UpdateColumn("deleted_at", nil)
+
+		return res.RowsAffected > 0
+	}
+}
+
+// IsSoftDeletable
+// T is soft deletable if it has a DeletedAt field, such as the one from Base
+func IsSoftDeletable[T any]() bool {
+	_, ok := reflect.TypeFor[T]().FieldByName("DeletedAt")
+	return ok
+}
+
 func NewSoftDeleteSearchHandler(tableName string) SearchHandler {
 	fieldName := "`deleted_at`"
 	if tableName != "" {
@@ -110,13 +159,18 @@ func IDsFromCommaSeparatedString(css string) []ID {
 }
 
 // NewDuplicateFieldCheckFunc
-// T must extend from Base which must contain id field
+// the stored record is found by the primary key of T, see PrimaryKeyOf
 func NewDuplicateFieldCheckFunc[T any](
 	db *gorm.DB, logger *gogger.Logger,
 	objectFieldName string,
 ) (func(context *gin.Context, objectForCheck *T) error, error) {
 	var dbFieldName string
 
+	primaryKey, err := PrimaryKeyOf[T](db)
+	if err != nil {
+		return nil, err
+	}
+
 	// runtime check
 	{
 		dbFieldNames, err := GetDatabaseFieldNameOf[T](db, objectFieldName)
@@ -133,7 +187,6 @@ func NewDuplicateFieldCheckFunc[T any](
 		record := reflect.ValueOf(objectForCheck).Elem()
 
 		valueField := record.FieldByName(objectFieldName)
-		idField := record.FieldByName("ID")
 
 		valueForCheck := record.FieldByName(objectFieldName).String()
 
@@ -144,16 +197,11 @@ func NewDuplicateFieldCheckFunc[T any](
 			return err
 		}
 
-		id := uint64(0)
-		if idField.CanUint() {
-			id = idField.Uint()
-		}
-
-		if id > 0 {
+		if id := primaryKey.Of(objectForCheck); !IsZeroKey(id) {
 			var old T
-			if err := db.Model(&old).Where("id = ?", id).First(&old).Error; err != nil {
+			if err := primaryKey.Where(db.Model(&old), []Key{id}).First(&old).Error; err != nil {
 				MakeErrorResponse(context, RestCoder.NotFound(), "record not found")
-				return fmt.Errorf("unable to find old record for id [%d]", id)
+				return fmt.Errorf("unable to find old record for id [%s]", FormatKey(id))
 			}
 
 			oldValue := reflect.ValueOf(old).FieldByName(objectFieldName).String()
@@ -0,0 +1,429 @@
+package gocrud
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const OpenAPIVersion = "3.0.3"
+
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIPathItem
+// lower case http method to operation
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas"`
+}
+
+type OpenAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       OpenAPIInfo                `json:"info"`
+	Paths      map[string]OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents          `json:"components"`
+}
+
+// OpenAPIRoute
+// a route to be documented, responds R with Data in json, or the raw ContentTypes if they are specified
+type OpenAPIRoute struct {
+	Method  string
+	Path    string // full path in gin style, such as `/user/one/:id`
+	Tag     string
+	Summary string
+
+	// SearchKeys
+	// query parameters of GET, properties of the json body of others
+	SearchKeys []string
+	QueryKeys  []string
+	HeaderKeys []string
+	// KeyTypes
+	// types of keys in path params, such as of `:id`, see openAPIKeySchemaOf
+	KeyTypes map[string]reflect.Type
+
+	Body             reflect.Type
+	BodyContentTypes []string // the body is binary if it is specified without Body
+
+	Data         reflect.Type
+	ContentTypes []string
+}
+
+// OpenAPI
+// collects routes of Setup, SetupM2MConnectorController and NewHttpFileSystemController,
+// and serves them as an OpenAPI 3 document with Handler
+type OpenAPI struct {
+	Info OpenAPIInfo
+
+	locker  sync.Mutex
+	paths   map[string]OpenAPIPathItem
+	schemas map[string]*OpenAPISchema
+}
+
+func NewOpenAPI(title, version string) *OpenAPI {
+	return &OpenAPI{
+		Info:    OpenAPIInfo{Title: title, Version: version},
+		paths:   map[string]OpenAPIPathItem{},
+		schemas: map[string]*OpenAPISchema{},
+	}
+}
+
+var (
+	openAPIPackagePattern  = regexp.MustCompile(`[\w\-./]+\.`)
+	openAPINonWordPattern  = regexp.MustCompile(`\W+`)
+	openAPIPathParamFormat = regexp.MustCompile(`[:*](\w+)`)
+
+	timeType            = reflect.TypeFor[time.Time]()
+	jsonRawMessageType  = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	openAPIIntegerNames = []string{"pageNum", "pageSize"}
+)
+
+// openAPISchemaNameOf
+// such as `Pagination_User` for `Pagination[github.com/allape/gocrud.User]`
+func openAPISchemaNameOf(t reflect.Type) string {
+	name := openAPIPackagePattern.ReplaceAllString(t.Name(), "")
+	name = strings.ReplaceAll(name, "[]", "List")
+	return strings.Trim(openAPINonWordPattern.ReplaceAllString(name, "_"), "_")
+}
+
+// OpenAPIPathOf
+// converts path params of gin to OpenAPI, such as `/one/:id` to `/one/{id}`
+func OpenAPIPathOf(path string) string {
+	return openAPIPathParamFormat.ReplaceAllString(path, "{$1}")
+}
+
+// openAPIKeySchemaOf
+// integers parsed by the default KeyCodec are documented as integers, others are strings, such as UUIDs
+func openAPIKeySchemaOf(t reflect.Type) *OpenAPISchema {
+	codec, err := KeyCodecFor(t)
+	if _, ok := codec.(reflectKeyCodec); err != nil || !ok || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return &OpenAPISchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	default:
+		return &OpenAPISchema{Type: "string"}
+	}
+}
+
+// SchemaOf
+// named structs are registered as components and referenced
+func (o *OpenAPI) SchemaOf(t reflect.Type) *OpenAPISchema {
+	o.locker.Lock()
+	defer o.locker.Unlock()
+	return o.schemaOf(t)
+}
+
+func (o *OpenAPI) schemaOf(t reflect.Type) *OpenAPISchema {
+	switch t {
+	case timeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case jsonRawMessageType:
+		return &OpenAPISchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return o.schemaOf(t.Elem())
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: o.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: o.schemaOf(t.Elem())}
+	case reflect.Struct:
+		// custom json format is unknown
+		if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+			return &OpenAPISchema{}
+		}
+
+		if t.Name() == "" {
+			return o.structSchemaOf(t)
+		}
+
+		name := openAPISchemaNameOf(t)
+		if _, ok := o.schemas[name]; !ok {
+			// placeholder for recursive types
+			o.schemas[name] = &OpenAPISchema{}
+			*o.schemas[name] = *o.structSchemaOf(t)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	default:
+		return &OpenAPISchema{}
+	}
+}
+
+func (o *OpenAPI) structSchemaOf(t reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		// fields of embedded structs are promoted
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			embedded := o.structSchemaOf(fieldType)
+			for key, property := range embedded.Properties {
+				// shadowed by the field of the outer struct
+				if _, ok := schema.Properties[key]; !ok {
+					schema.Properties[key] = property
+				}
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = o.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	slices.Sort(schema.Required)
+	schema.Required = slices.Compact(schema.Required)
+
+	return schema
+}
+
+// envelopeOf
+// schema of R with data as R.Data
+func (o *OpenAPI) envelopeOf(data *OpenAPISchema) *OpenAPISchema {
+	schema := o.structSchemaOf(reflect.TypeFor[R[any]]())
+
+	field, _ := reflect.TypeFor[R[any]]().FieldByName("Data")
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	schema.Properties[name] = data
+
+	return schema
+}
+
+// Add
+// documents a route, the route with the same method and path will be replaced
+func (o *OpenAPI) Add(route OpenAPIRoute) {
+	o.locker.Lock()
+	defer o.locker.Unlock()
+
+	path := OpenAPIPathOf(route.Path)
+	method := strings.ToLower(route.Method)
+
+	operation := &OpenAPIOperation{
+		OperationID: strings.Trim(method+"_"+openAPINonWordPattern.ReplaceAllString(path, "_"), "_"),
+		Summary:     route.Summary,
+		Responses:   map[string]OpenAPIResponse{},
+	}
+	if route.Tag != "" {
+		operation.Tags = []string{route.Tag}
+	}
+
+	for _, match := range openAPIPathParamFormat.FindAllStringSubmatch(route.Path, -1) {
+		schema := &OpenAPISchema{Type: "string"}
+		if t, ok := route.KeyTypes[match[1]]; ok {
+			schema = openAPIKeySchemaOf(t)
+		} else if slices.Contains(openAPIIntegerNames, match[1]) {
+			schema = &OpenAPISchema{Type: "integer", Format: "int64"}
+		}
+		operation.Parameters = append(operation.Parameters, OpenAPIParameter{
+			Name: match[1], In: "path", Required: true, Schema: schema,
+		})
+	}
+
+	queryKeys := route.QueryKeys
+	if route.Method == http.MethodGet {
+		queryKeys = append(slices.Clone(route.SearchKeys), queryKeys...)
+	}
+	for _, key := range queryKeys {
+		operation.Parameters = append(operation.Parameters, OpenAPIParameter{
+			Name: key, In: "query", Schema: &OpenAPISchema{Type: "string"},
+		})
+	}
+	for _, key := range route.HeaderKeys {
+		operation.Parameters = append(operation.Parameters, OpenAPIParameter{
+			Name: key, In: "header", Schema: &OpenAPISchema{Type: "string"},
+		})
+	}
+
+	switch {
+	case route.Body != nil:
+		contentTypes := route.BodyContentTypes
+		if len(contentTypes) == 0 {
+			contentTypes = []string{"application/json"}
+		}
+		operation.RequestBody = &OpenAPIRequestBody{Required: true, Content: map[string]OpenAPIMediaType{}}
+		for _, contentType := range contentTypes {
+			operation.RequestBody.Content[contentType] = OpenAPIMediaType{Schema: o.schemaOf(route.Body)}
+		}
+	case len(route.BodyContentTypes) > 0:
+		operation.RequestBody = &OpenAPIRequestBody{Required: true, Content: map[string]OpenAPIMediaType{}}
+		for _, contentType := range route.BodyContentTypes {
+			operation.RequestBody.Content[contentType] = OpenAPIMediaType{Schema: &OpenAPISchema{Type: "string", Format: "binary"}}
+		}
+	case route.Method != http.MethodGet && len(route.SearchKeys) > 0:
+		search := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+		for _, key := range route.SearchKeys {
+			search.Properties[key] = &OpenAPISchema{Type: "string"}
+		}
+		operation.RequestBody = &OpenAPIRequestBody{Content: map[string]OpenAPIMediaType{
+			"application/json": {Schema: search},
+		}}
+	}
+
+	if len(route.ContentTypes) > 0 {
+		response := OpenAPIResponse{Description: "OK", Content: map[string]OpenAPIMediaType{}}
+		for _, contentType := range route.ContentTypes {
+			response.Content[contentType] = OpenAPIMediaType{Schema: &OpenAPISchema{Type: "string", Format: "binary"}}
+		}
+		operation.Responses["200"] = response
+	} else {
+		data := &OpenAPISchema{}
+		if route.Data != nil {
+			data = o.schemaOf(route.Data)
+		}
+		operation.Responses["200"] = OpenAPIResponse{
+			Description: "OK",
+			Content: map[string]OpenAPIMediaType{
+				"application/json": {Schema: o.envelopeOf(data)},
+			},
+		}
+	}
+
+	if _, ok := o.paths[path]; !ok {
+		o.paths[path] = OpenAPIPathItem{}
+	}
+	o.paths[path][method] = operation
+}
+
+func (o *OpenAPI) Document() *OpenAPIDocument {
+	o.locker.Lock()
+	defer o.locker.Unlock()
+
+	document := &OpenAPIDocument{
+		OpenAPI:    OpenAPIVersion,
+		Info:       o.Info,
+		Paths:      make(map[string]OpenAPIPathItem, len(o.paths)),
+		Components: OpenAPIComponents{Schemas: make(map[string]*OpenAPISchema, len(o.schemas))},
+	}
+	for path, item := range o.paths {
+		document.Paths[path] = item
+	}
+	for name, schema := range o.schemas {
+		document.Components.Schemas[name] = schema
+	}
+
+	return document
+}
+
+// Handler
+// serves Document in json, such as `engine.GET("/openapi.json", openAPI.Handler())`
+func (o *OpenAPI) Handler() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.JSON(http.StatusOK, o.Document())
+	}
+}
+
+// openAPIPathOf
+// full path of relativePath in group
+func openAPIPathOf(group *gin.RouterGroup, relativePath string) string {
+	if fullPath := strings.TrimRight(group.BasePath(), "/") + relativePath; fullPath != "" {
+		return fullPath
+	}
+	return "/"
+}
+
+// openAPITagOf
+// the last segment of the base path of group
+func openAPITagOf(group *gin.RouterGroup) string {
+	segments := strings.Split(strings.Trim(group.BasePath(), "/"), "/")
+	return segments[len(segments)-1]
+}
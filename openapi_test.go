@@ -0,0 +1,187 @@
+package gocrud
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/allape/gogger"
+)
+
+func TestOpenAPI(t *testing.T) {
+	db, engine, err := basicSetup("TestOpenAPI.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openAPI := NewOpenAPI("gocrud", "1.0.0")
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		EnableGetAll:   true,
+		DisablePatch:   true,
+		OpenAPI:        openAPI,
+		SearchHandlers: BaseSearchHandlers(SearchHandlers{"like_name": KeywordLike("name", nil)}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&Document{}, &Membership{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/document"), db, nil, &Crud[Document]{OpenAPI: openAPI})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/membership"), db, nil, &Crud[Membership]{OpenAPI: openAPI})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetupM2MConnectorController[UserTag](
+		engine.Group("/user-tag"), db, gogger.New("controller:user-tag"),
+		"UserID", "TagID",
+		&SetupM2MConnectorControllerOptions[UserTag]{OpenAPI: openAPI},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewHttpFileSystemObjectConfig[DemoHttpFileObject](true, masterKey, hashSalt)
+	config.OpenAPI = openAPI
+
+	err = NewHttpFileSystemObjectController[DemoHttpFileObject](
+		engine.Group("/files"), db, gogger.New("oss:openapi"),
+		TestDataDir, config,
+		"",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine.GET("/openapi.json", openAPI.Handler())
+
+	var binding = address.openAPI.NewAddress(0)
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	content, err := fetchBytes(http.MethodGet, "http://"+binding+"/openapi.json", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var document OpenAPIDocument
+	err = json.Unmarshal(content, &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if document.OpenAPI != OpenAPIVersion || document.Info.Title != "gocrud" {
+		t.Fatalf("unexpected document header %s %v", document.OpenAPI, document.Info)
+	}
+
+	operationOf := func(path, method string) *OpenAPIOperation {
+		operation := document.Paths[path][method]
+		if operation == nil {
+			t.Fatalf("expected %s %s to be documented", method, path)
+		}
+		return operation
+	}
+
+	page := operationOf("/user/page/{pageNum}/{pageSize}", "get")
+	names := make([]string, len(page.Parameters))
+	for i, parameter := range page.Parameters {
+		names[i] = parameter.Name
+	}
+	for _, name := range []string{"pageNum", "pageSize", "in_id", "like_name"} {
+		if !slices.Contains(names, name) {
+			t.Fatalf("expected parameter %s in %v", name, names)
+		}
+	}
+
+	search := operationOf("/user/page/{pageNum}/{pageSize}", "post").RequestBody.Content["application/json"].Schema
+	if search.Properties["like_name"] == nil {
+		t.Fatal("expected search keys in body of POST")
+	}
+
+	one := operationOf("/user/one/{id}", "get").Responses["200"].Content["application/json"].Schema
+	if one.Properties["c"] == nil || one.Properties["m"] == nil || one.Properties["d"].Ref != "#/components/schemas/User" {
+		t.Fatalf("expected R envelope of User, got %v", one.Properties)
+	}
+
+	// typeOf
+	// type of path param name of operation
+	typeOf := func(operation *OpenAPIOperation, name string) string {
+		for _, parameter := range operation.Parameters {
+			if parameter.Name == name && parameter.In == "path" {
+				return parameter.Schema.Type
+			}
+		}
+		t.Fatalf("expected path parameter %s", name)
+		return ""
+	}
+
+	for _, param := range []struct {
+		operation *OpenAPIOperation
+		name      string
+		expected  string
+	}{
+		{operationOf("/user/one/{id}", "get"), "id", "integer"},
+		{operationOf("/user/{id}", "delete"), "id", "string"},
+		{operationOf("/document/one/{id}", "get"), "id", "string"},
+		{operationOf("/document/{id}", "patch"), "id", "string"},
+		{operationOf("/membership/one/{org_id}/{code}", "get"), "org_id", "integer"},
+		{operationOf("/membership/one/{org_id}/{code}", "get"), "code", "string"},
+		{operationOf("/user-tag/save/{deleteByField}/{deleteById}", "post"), "deleteById", "integer"},
+	} {
+		if actual := typeOf(param.operation, param.name); actual != param.expected {
+			t.Fatalf("expected %s to be %s, got %s", param.name, param.expected, actual)
+		}
+	}
+
+	if document.Paths["/user/{id}"]["patch"] != nil {
+		t.Fatal("expected patch to be undocumented")
+	}
+
+	operationOf("/user", "put")
+	operationOf("/user/all", "post")
+	operationOf("/user-tag/save", "put")
+	operationOf("/user-tag/save/{deleteByField}/{deleteById}", "post")
+	operationOf("/user-tag", "delete")
+	operationOf("/files/{filepath}", "get")
+	operationOf("/files/{filepath}", "post")
+
+	user := document.Components.Schemas["User"]
+	if user == nil {
+		t.Fatal("expected schema of User")
+	}
+	for _, name := range []string{"id", "createdAt", "deletedAt", "name", "age"} {
+		if user.Properties[name] == nil {
+			t.Fatalf("expected property %s of User", name)
+		}
+	}
+	if user.Properties["createdAt"].Format != "date-time" {
+		t.Fatalf("expected createdAt to be date-time, got %s", user.Properties["createdAt"].Format)
+	}
+	if slices.Contains(user.Required, "deletedAt") || !slices.Contains(user.Required, "name") {
+		t.Fatalf("unexpected required fields %v", user.Required)
+	}
+
+	if document.Components.Schemas["Pagination_User"] != nil {
+		t.Fatal("expected Pagination_User to be absent without EnablePagination")
+	}
+	if name := openAPISchemaNameOf(reflect.TypeFor[Pagination[User]]()); name != "Pagination_User" {
+		t.Fatalf("expected Pagination_User, got %s", name)
+	}
+}
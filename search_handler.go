@@ -1,7 +1,10 @@
 package gocrud
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"maps"
 	"net/http"
 	"net/url"
@@ -18,7 +21,16 @@ type (
 	SearchHandlers = map[string]SearchHandler
 )
 
-const ContextKeyHandledSearch = "gocrud:crud:hanldedsearch"
+const (
+	ContextKeyHandledSearch  = "gocrud:crud:hanldedsearch"
+	ContextKeyHandledSort    = "gocrud:crud:handledsort"
+	ContextKeySearchValues   = "gocrud:crud:searchvalues"
+	ContextKeySelectedFields = "gocrud:crud:selectedfields"
+)
+
+// SearchKeyFields
+// reserved search key for selecting columns, see Crud.SelectableFields
+const SearchKeyFields = "fields"
 
 func GetHandledSearch(context *gin.Context) []string {
 	return context.GetStringSlice(ContextKeyHandledSearch)
@@ -28,6 +40,37 @@ func SetHandledSearch(context *gin.Context, handledSearch []string) {
 	context.Set(ContextKeyHandledSearch, handledSearch)
 }
 
+// GetSelectedFields
+// returns object field names selected by search key `fields`
+func GetSelectedFields(context *gin.Context) ([]string, bool) {
+	if value, ok := context.Get(ContextKeySelectedFields); ok {
+		return value.([]string), true
+	}
+	return nil, false
+}
+
+func SetSelectedFields(context *gin.Context, objectFieldNames []string) {
+	context.Set(ContextKeySelectedFields, objectFieldNames)
+}
+
+// SortKey
+// a sort applied by SortBy
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+func GetHandledSort(context *gin.Context) []SortKey {
+	if value, ok := context.Get(ContextKeyHandledSort); ok {
+		return value.([]SortKey)
+	}
+	return nil
+}
+
+func AddHandledSort(context *gin.Context, sortKey SortKey) {
+	context.Set(ContextKeyHandledSort, append(GetHandledSort(context), sortKey))
+}
+
 func HandleSearch(context *gin.Context, db *gorm.DB, searchHandlers SearchHandlers) (*gorm.DB, error) {
 	if searchHandlers == nil {
 		return db, nil
@@ -40,9 +83,10 @@ func HandleSearch(context *gin.Context, db *gorm.DB, searchHandlers SearchHandle
 
 	handledSearch := make([]string, 0, len(searches))
 
-	for key, value := range searches {
+	// sorted keys make the order of `ORDER BY` stable
+	for _, key := range slices.Sorted(maps.Keys(searches)) {
 		if handler, ok := searchHandlers[key]; ok {
-			db, err = handler(db, value, context)
+			db, err = handler(db, searches[key], context)
 			if err != nil {
 				return nil, err
 			}
@@ -168,6 +212,21 @@ func KeywordIDIn(field string, vt ValueTransformer[[]ID, []ID]) SearchHandler {
 	})
 }
 
+// KeywordKeyIn
+// KeywordIDIn for keys parsed by codec, such as `KeywordKeyIn("id", codec, nil)` with a codec of KeyCodecOf T
+func KeywordKeyIn(field string, codec KeyCodec, vt ValueTransformer[[]Key, []Key]) SearchHandler {
+	return KeywordStatement(field, OperatorIn, func(value string) any {
+		keys := KeysFromCommaSeparatedString(codec, value)
+		if vt != nil {
+			keys = vt(keys)
+			if len(keys) == 0 {
+				return nil
+			}
+		}
+		return keys
+	})
+}
+
 func KeywordLike(field string, vt ValueTransformer[string, any]) SearchHandler {
 	return KeywordStatement(field, OperatorLike, func(value string) any {
 		var anyValue any = value
@@ -183,13 +242,13 @@ func KeywordEqual(field string, vt ValueTransformer[string, any]) SearchHandler
 }
 
 func SortBy(field string) SearchHandler {
-	return func(db *gorm.DB, values []string, _ *gin.Context) (*gorm.DB, error) {
+	return func(db *gorm.DB, values []string, context *gin.Context) (*gorm.DB, error) {
 		if value, ok := PickFirstValuableString(values); ok {
-			sort := "ASC"
-			if strings.TrimSpace(strings.ToLower(value)) == "desc" {
-				sort = "DESC"
+			desc := strings.TrimSpace(strings.ToLower(value)) == "desc"
+			db = db.Order(fmt.Sprintf("`%s` %s", field, Ternary(desc, "DESC", "ASC")))
+			if context != nil {
+				AddHandledSort(context, SortKey{Column: field, Desc: desc})
 			}
-			db = db.Order(fmt.Sprintf("`%s` %s", field, sort))
 		}
 		return db, nil
 	}
@@ -216,18 +275,34 @@ func OverflowedArrayTrimmerFilter[T any](max int) func([]T) []T {
 	}
 }
 
+// GetSearchValuesFromContext
+// values are cached in context, because request body can only be read once
 func GetSearchValuesFromContext(context *gin.Context) (url.Values, error) {
+	if cached, ok := context.Get(ContextKeySearchValues); ok {
+		return cached.(url.Values), nil
+	}
+
 	var searchValues = make(url.Values)
 
 	if context.Request.Method != http.MethodGet {
-		var bodyPayload map[string]string
+		var bodyPayload map[string]json.RawMessage
 		err := context.ShouldBindJSON(&bodyPayload)
-		if err != nil {
+		// empty body means there is no search value in body
+		if err != nil && !errors.Is(err, io.EOF) {
 			return nil, err
 		}
 
 		for key, value := range bodyPayload {
-			searchValues[key] = []string{value}
+			if string(value) == "null" {
+				continue
+			}
+
+			// non-string values, such as FilterNode, are kept in json
+			var str string
+			if json.Unmarshal(value, &str) != nil {
+				str = string(value)
+			}
+			searchValues[key] = []string{str}
 		}
 	}
 
@@ -244,5 +319,7 @@ func GetSearchValuesFromContext(context *gin.Context) (url.Values, error) {
 		searchValues[key] = value
 	}
 
+	context.Set(ContextKeySearchValues, searchValues)
+
 	return searchValues, nil
 }
@@ -0,0 +1,127 @@
+package gocrud
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ContextKeyTenant
+// the tenant resolved by Tenancy is cached in context
+const ContextKeyTenant = "gocrud:tenant"
+
+var (
+	NilTenantResolverError = errors.New("tenant resolver is nil")
+	NoTenantError          = errors.New("no tenant")
+	CrossTenantError       = errors.New("record belongs to another tenant")
+)
+
+// Tenancy
+// isolates rows by a tenant column, every read is filtered by the tenant of the request,
+// every write is stamped with it, and records of other tenants can not be accessed by id.
+type Tenancy struct {
+	// Field
+	// object field name of the tenant column, such as `TenantID`
+	Field string
+	// Resolve
+	// returns the tenant of the request, such as the tenant id of the authenticated user,
+	// a non-nil error or a nil tenant denies the request with Coder.Forbidden()
+	Resolve func(context *gin.Context) (any, error)
+}
+
+// tenantColumnOf
+// returns the database column of Tenancy.Field in T
+func tenantColumnOf[T any](db *gorm.DB, tenancy *Tenancy) (string, error) {
+	if tenancy.Resolve == nil {
+		return "", NilTenantResolverError
+	}
+
+	if _, ok := reflect.TypeFor[T]().FieldByName(tenancy.Field); !ok {
+		return "", fmt.Errorf("tenant field %s not found", tenancy.Field)
+	}
+
+	columns, err := GetDatabaseFieldNameOf[T](db, tenancy.Field)
+	if err != nil {
+		return "", err
+	}
+
+	return columns[0], nil
+}
+
+// Tenant
+// resolves the tenant of context once, and caches it in context
+func (t *Tenancy) Tenant(context *gin.Context) (any, error) {
+	if tenant, ok := context.Get(ContextKeyTenant); ok {
+		return tenant, nil
+	}
+
+	tenant, err := t.Resolve(context)
+	if err != nil {
+		return nil, err
+	} else if tenant == nil {
+		return nil, NoTenantError
+	}
+
+	context.Set(ContextKeyTenant, tenant)
+
+	return tenant, nil
+}
+
+// Scope
+// filters db by the tenant
+func (t *Tenancy) Scope(db *gorm.DB, column string, tenant any) *gorm.DB {
+	return db.Where(fmt.Sprintf("`%s` = ?", column), tenant)
+}
+
+// Foreign
+// filters db by tenants other than the tenant
+func (t *Tenancy) Foreign(db *gorm.DB, column string, tenant any) *gorm.DB {
+	return db.Where(fmt.Sprintf("`%s` <> ?", column), tenant)
+}
+
+// Stamp
+// sets the tenant to Tenancy.Field of record, record should be a pointer of struct
+func (t *Tenancy) Stamp(record any, tenant any) error {
+	field, value, err := t.fieldOf(record, tenant)
+	if err != nil {
+		return err
+	}
+
+	field.Set(value)
+
+	return nil
+}
+
+// Check
+// returns CrossTenantError if Tenancy.Field of record is not the tenant, record should be a pointer of struct
+func (t *Tenancy) Check(record any, tenant any) error {
+	field, value, err := t.fieldOf(record, tenant)
+	if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(field.Interface(), value.Interface()) {
+		return CrossTenantError
+	}
+
+	return nil
+}
+
+// fieldOf
+// returns Tenancy.Field of record, and the tenant converted to the type of it
+func (t *Tenancy) fieldOf(record any, tenant any) (reflect.Value, reflect.Value, error) {
+	field := reflect.ValueOf(record).Elem().FieldByName(t.Field)
+	if !field.IsValid() {
+		return reflect.Value{}, reflect.Value{}, fmt.Errorf("tenant field %s not found", t.Field)
+	}
+
+	value := reflect.ValueOf(tenant)
+	if !value.Type().ConvertibleTo(field.Type()) {
+		return reflect.Value{}, reflect.Value{}, fmt.Errorf("tenant of %s is not convertible to %s", value.Type(), field.Type())
+	}
+
+	return field, value.Convert(field.Type()), nil
+}
@@ -0,0 +1,353 @@
+package gocrud
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/allape/gogger"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TenantUser struct {
+	Base
+	TenantID string `json:"tenantId"`
+	Name     string `json:"name"`
+}
+
+type TenantUserTag struct {
+	TenantID string `json:"tenantId"`
+	UserID   ID     `json:"userId" gorm:"primaryKey"`
+	TagID    ID     `json:"tagId" gorm:"primaryKey"`
+}
+
+type TenantHttpFileObject struct {
+	HttpFileSystemObjectBase
+	TenantID string `json:"tenantId" gorm:"primaryKey"`
+}
+
+type tenantTransport string
+
+func (t tenantTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Tenant", string(t))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newHeaderTenancy() *Tenancy {
+	return &Tenancy{
+		Field: "TenantID",
+		Resolve: func(context *gin.Context) (any, error) {
+			if tenant := context.GetHeader("X-Tenant"); tenant != "" {
+				return tenant, nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func isForbiddenError(err error) bool {
+	var responseError *ResponseError
+	return errors.As(err, &responseError) && responseError.Code == RestCoder.Forbidden()
+}
+
+func TestTenancy(t *testing.T) {
+	db, engine, err := basicSetup("TestTenancy.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&TenantUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[TenantUser]{
+		EnableGetAll: true,
+		Tenancy:      newHeaderTenancy(),
+		WillGetOne: func(context *gin.Context, db *gorm.DB) *gorm.DB {
+			return db.Where("name <> ?", "hidden")
+		},
+		WillSave: func(record *TenantUser, context *gin.Context, db *gorm.DB) {
+			if record.Name == "moved" {
+				record.TenantID = "alice"
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.tenancy.NewAddress(0)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudyOf := func(tenant string) *Crudy[TenantUser] {
+		crudy, err := NewCrudy[TenantUser](addr+"/user", CrudyBasicOptions[TenantUser]{
+			HttpClient: &http.Client{Transport: tenantTransport(tenant)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crudy
+	}
+
+	alice := crudyOf("alice")
+	bob := crudyOf("bob")
+
+	aliceUser, err := alice.Save(&TenantUser{Name: "a1"})
+	if err != nil {
+		t.Fatal(err)
+	} else if aliceUser.TenantID != "alice" {
+		t.Fatalf("expected tenant alice, got %s", aliceUser.TenantID)
+	}
+
+	bobUser, err := bob.Save(&TenantUser{Name: "b1", TenantID: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	} else if bobUser.TenantID != "bob" {
+		t.Fatalf("expected tenant to be stamped with bob, got %s", bobUser.TenantID)
+	}
+
+	users, err := alice.All(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(users) != 1 || users[0].ID != aliceUser.ID {
+		t.Fatalf("expected only the user of alice, got %v", users)
+	}
+
+	count, err := bob.Count(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 1 {
+		t.Fatalf("expected 1 user of bob, got %d", count)
+	}
+
+	_, err = bob.One(aliceUser.ID)
+	if err == nil {
+		t.Fatal("expected user of alice to be not found for bob")
+	}
+
+	_, err = bob.Save(&TenantUser{Base: Base{ID: aliceUser.ID}, Name: "hijacked"})
+	if !isForbiddenError(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+
+	_, err = bob.Save(&TenantUser{Name: "moved"})
+	if !isForbiddenError(err) {
+		t.Fatalf("expected forbidden for the tenant changed by WillSave, got %v", err)
+	}
+
+	_, err = bob.Patch(aliceUser.ID, map[string]any{"name": "hijacked"})
+	var responseError *ResponseError
+	if !errors.As(err, &responseError) || responseError.Code != RestCoder.NotFound() {
+		t.Fatalf("expected user of alice to be not found for bob, got %v", err)
+	}
+
+	_, err = bob.Delete(aliceUser.ID)
+	if !isForbiddenError(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+
+	_, err = crudyOf("").All(nil)
+	if !isForbiddenError(err) {
+		t.Fatalf("expected forbidden without tenant, got %v", err)
+	}
+
+	hidden, err := alice.Save(&TenantUser{Name: "hidden"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = alice.One(hidden.ID)
+	if err == nil {
+		t.Fatal("expected scoped db of WillGetOne to be used")
+	}
+
+	var stored TenantUser
+	err = db.First(&stored, aliceUser.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	} else if stored.Name != "a1" || stored.TenantID != "alice" || stored.DeletedAt != nil {
+		t.Fatalf("expected user of alice to be untouched, got %v", stored)
+	}
+}
+
+func TestM2MTenancy(t *testing.T) {
+	db, engine, err := basicSetup("TestM2MTenancy.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&TenantUserTag{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetupM2MConnectorController[TenantUserTag](
+		engine.Group("/user-tag"), db, gogger.New("controller:user-tag"),
+		"UserID", "TagID",
+		&SetupM2MConnectorControllerOptions[TenantUserTag]{Tenancy: newHeaderTenancy()},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.tenancy.NewAddress(1)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	handlerOf := func(tenant string) *M2MConnectorHandler[TenantUser, Tag, TenantUserTag] {
+		handler, err := NewM2MConnectorHandler[TenantUser, Tag, TenantUserTag](
+			addr+"/user-tag", &http.Client{Transport: tenantTransport(tenant)}, nil,
+			"UserID", "TagID",
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return handler
+	}
+
+	alice := handlerOf("alice")
+	bob := handlerOf("bob")
+
+	_, err = alice.Save([]TenantUserTag{{UserID: 1, TagID: 2}, {UserID: 1, TagID: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := bob.GetAll([]ID{1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(list) != 0 {
+		t.Fatalf("expected no connector for bob, got %v", list)
+	}
+
+	_, err = bob.Save([]TenantUserTag{{UserID: 1, TagID: 2}})
+	if !isForbiddenError(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+
+	_, err = bob.SaveAfterDelete("UserID", 1, []TenantUserTag{{UserID: 1, TagID: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := bob.Delete(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 0 {
+		t.Fatalf("expected nothing to be deleted, got %d", count)
+	}
+
+	list, err = alice.GetAll([]ID{1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(list) != 2 {
+		t.Fatalf("expected connectors of alice to be untouched, got %v", list)
+	}
+	for _, connector := range list {
+		if connector.TenantID != "alice" {
+			t.Fatalf("expected tenant alice, got %s", connector.TenantID)
+		}
+	}
+
+	list, err = bob.GetAll([]ID{1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(list) != 1 || list[0].TagID != 4 || list[0].TenantID != "bob" {
+		t.Fatalf("expected the connector of bob, got %v", list)
+	}
+}
+
+func TestHttpFileSystemObjectTenancy(t *testing.T) {
+	var binding = address.tenancy.NewAddress(2)
+
+	db, engine, err := basicSetup("TestHttpFileSystemObjectTenancy.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewHttpFileSystemObjectConfig[TenantHttpFileObject](true, masterKey, hashSalt)
+	config.Tenancy = newHeaderTenancy()
+
+	err = NewHttpFileSystemObjectController[TenantHttpFileObject](
+		engine.Group("/files"), db, gogger.New("oss:tenancy"),
+		TestDataDir, config,
+		"",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	content, err := NewRandomBytes(MegaByte)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(tenant string) string {
+		result, err := fetchJSON[string](http.MethodPost, "http://"+binding+"/files/tenancy.bin", bytes.NewReader(content), map[string]string{
+			"X-Tenant": tenant,
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if result.Code != RestCoder.OK() {
+			t.Fatalf("response status is not ok, got %s: %s", result.Code, result.Message)
+		}
+		return fmt.Sprintf("http://%s/files%s", binding, result.Data)
+	}
+
+	download := func(url, tenant string) {
+		data, err := fetchBytes(http.MethodGet, url, nil, map[string]string{"X-Tenant": tenant})
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(data, content) {
+			t.Fatalf("file content of %s is not same", tenant)
+		}
+	}
+
+	url := upload("alice")
+	download(url, "alice")
+
+	notFound, err := fetchJSON[any](http.MethodGet, url, nil, map[string]string{"X-Tenant": "bob"})
+	if err != nil {
+		t.Fatal(err)
+	} else if notFound.Code != RestCoder.NotFound() {
+		t.Fatalf("expected not found for bob, got %s: %s", notFound.Code, notFound.Message)
+	}
+
+	forbidden, err := fetchJSON[any](http.MethodGet, url, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if forbidden.Code != RestCoder.Forbidden() {
+		t.Fatalf("expected forbidden without tenant, got %s: %s", forbidden.Code, forbidden.Message)
+	}
+
+	if upload("bob") != url {
+		t.Fatal("expected the same file to be shared")
+	}
+	download(url, "bob")
+}
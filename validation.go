@@ -0,0 +1,151 @@
+package gocrud
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationTags
+// struct tags evaluated by Validator, `binding` is the tag of gin
+var ValidationTags = []string{"binding", "validate"}
+
+// validatorEmbeddedName
+// name of embedded structs in namespaces, which will be dropped from FieldError.Field
+const validatorEmbeddedName = "~"
+
+// FieldError
+// Field is the json path of the field, such as `name` or `items[0].name`
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type FieldErrors []FieldError
+
+func (errs FieldErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// WithPrefix
+// prefixes fields, such as `[0]` for records in an array
+func (errs FieldErrors) WithPrefix(prefix string) FieldErrors {
+	prefixed := make(FieldErrors, len(errs))
+	for i, err := range errs {
+		field := err.Field
+		if !strings.HasPrefix(field, "[") {
+			field = "." + field
+		}
+		prefixed[i] = FieldError{Field: prefix + field, Rule: err.Rule, Message: err.Message}
+	}
+	return prefixed
+}
+
+// Validator
+// validates structs with ValidationTags, field errors are named by json tags
+type Validator struct {
+	validates []*validator.Validate
+}
+
+var DefaultValidator = NewValidator()
+
+func jsonNameOfField(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		if field.Anonymous {
+			return validatorEmbeddedName
+		}
+		return field.Name
+	}
+	return name
+}
+
+func NewValidator() *Validator {
+	v := &Validator{}
+	for _, tag := range ValidationTags {
+		validate := validator.New(validator.WithRequiredStructEnabled())
+		validate.SetTagName(tag)
+		validate.RegisterTagNameFunc(jsonNameOfField)
+		v.validates = append(v.validates, validate)
+	}
+	return v
+}
+
+// RegisterValidation
+// registers a custom rule for all ValidationTags, such as `validate:"even"`
+func (v *Validator) RegisterValidation(rule string, fn validator.Func) error {
+	for _, validate := range v.validates {
+		err := validate.RegisterValidation(rule, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldOf
+// converts namespace of validator to a json path, such as `User.~.name` to `name`
+func fieldOf(namespace string) string {
+	segments := strings.Split(namespace, ".")[1:]
+
+	field := ""
+	for _, segment := range segments {
+		if segment == validatorEmbeddedName {
+			continue
+		}
+		if field != "" && !strings.HasPrefix(segment, "[") {
+			field += "."
+		}
+		field += segment
+	}
+
+	return field
+}
+
+func messageOf(field string, err validator.FieldError) string {
+	if err.Param() == "" {
+		return fmt.Sprintf("%s does not satisfy %s", field, err.Tag())
+	}
+	return fmt.Sprintf("%s does not satisfy %s=%s", field, err.Tag(), err.Param())
+}
+
+// Validate
+// returns FieldErrors if record is invalid, record should be a struct or a pointer of struct
+func (v *Validator) Validate(record any) error {
+	var fieldErrors FieldErrors
+
+	for _, validate := range v.validates {
+		err := validate.Struct(record)
+		if err == nil {
+			continue
+		}
+
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+
+		for _, validationError := range validationErrors {
+			field := fieldOf(validationError.Namespace())
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   field,
+				Rule:    validationError.Tag(),
+				Message: messageOf(field, validationError),
+			})
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return fieldErrors
+	}
+
+	return nil
+}
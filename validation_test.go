@@ -0,0 +1,179 @@
+package gocrud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/allape/gogger"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type ValidatedUser struct {
+	Base
+	Name  string `json:"name"            binding:"required"`
+	Age   int    `json:"age"             validate:"gte=0,lte=150"`
+	Email string `json:"email,omitempty" validate:"omitempty,email"`
+}
+
+type ValidatedUserTag struct {
+	UserID ID     `json:"userId" gorm:"primaryKey"`
+	TagID  ID     `json:"tagId"  gorm:"primaryKey"`
+	Note   string `json:"note"   validate:"max=3"`
+}
+
+type validatedOrder struct {
+	Items []validatedItem `json:"items" binding:"dive" validate:"dive"`
+}
+
+type validatedItem struct {
+	Name  string `json:"name" binding:"required"`
+	Count int    `validate:"even"`
+}
+
+func fieldsOf(errs FieldErrors) []string {
+	fields := make([]string, len(errs))
+	for i, err := range errs {
+		fields[i] = err.Field
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+func TestValidator(t *testing.T) {
+	v := NewValidator()
+
+	err := v.RegisterValidation("even", func(fl validator.FieldLevel) bool {
+		return fl.Field().Int()%2 == 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.Validate(&ValidatedUser{Name: "name", Age: 18})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.Validate(&ValidatedUser{Age: 200, Email: "email"})
+	var errs FieldErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected FieldErrors, got %v", err)
+	} else if fields := fieldsOf(errs); !slices.Equal(fields, []string{"age", "email", "name"}) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	err = v.Validate(&validatedOrder{Items: []validatedItem{{Name: "a", Count: 2}, {Count: 1}}})
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected FieldErrors, got %v", err)
+	} else if fields := fieldsOf(errs); !slices.Equal(fields, []string{"items[1].Count", "items[1].name"}) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	if fields := fieldsOf(errs.WithPrefix("[0]")); !slices.Equal(fields, []string{"[0].items[1].Count", "[0].items[1].name"}) {
+		t.Fatalf("unexpected prefixed fields %v", fields)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	db, engine, err := basicSetup("TestValidation.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(&ValidatedUser{}, &ValidatedUserTag{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[ValidatedUser]{
+		Validators: []func(record *ValidatedUser, context *gin.Context) FieldErrors{
+			func(record *ValidatedUser, _ *gin.Context) FieldErrors {
+				if record.Name == "root" {
+					return FieldErrors{{Field: "name", Rule: "reserved", Message: "name is reserved"}}
+				}
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetupM2MConnectorController[ValidatedUserTag](
+		engine.Group("/user-tag"), db, gogger.New("controller:user-tag"),
+		"UserID", "TagID",
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.validation.NewAddress(0)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	put := func(url string, body any) *R[FieldErrors] {
+		content, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := fetchJSON[FieldErrors](http.MethodPut, url, bytes.NewReader(content), map[string]string{
+			"Content-Type": "application/json",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := put(addr+"/user", ValidatedUser{Age: -1})
+	if res.Code != RestCoder.BadRequest() {
+		t.Fatalf("expected bad request, got %s: %s", res.Code, res.Message)
+	} else if fields := fieldsOf(res.Data); !slices.Equal(fields, []string{"age", "name"}) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	res = put(addr+"/user", ValidatedUser{Name: "root"})
+	if res.Code != RestCoder.BadRequest() || len(res.Data) != 1 || res.Data[0].Rule != "reserved" {
+		t.Fatalf("expected custom validator to fail, got %s: %v", res.Code, res.Data)
+	}
+
+	crudy, err := NewCrudy[ValidatedUser](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := crudy.Save(&ValidatedUser{Name: "alice", Age: 18})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = crudy.Patch(user.ID, map[string]any{"age": 151})
+	if err == nil {
+		t.Fatal("expected patch to be validated")
+	}
+
+	records, err := crudy.BatchSave([]ValidatedUser{{Name: "bob"}, {Name: "carol", Email: "carol"}})
+	if err == nil {
+		t.Fatalf("expected batch save to be validated, got %v", records)
+	}
+
+	res = put(addr+"/user-tag/save", []ValidatedUserTag{{UserID: 1, TagID: 1, Note: "ok"}, {UserID: 1, TagID: 2, Note: "too long"}})
+	if res.Code != RestCoder.BadRequest() {
+		t.Fatalf("expected bad request, got %s: %s", res.Code, res.Message)
+	} else if fields := fieldsOf(res.Data); !slices.Equal(fields, []string{"[1].note"}) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
@@ -0,0 +1,543 @@
+package gocrud
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/allape/gogger"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	HeaderKeyWebhookDelivery  = "X-Webhook-Delivery"
+	HeaderKeyWebhookEvent     = "X-Webhook-Event"
+	HeaderKeyWebhookTimestamp = "X-Webhook-Timestamp"
+	// HeaderKeyWebhookSignature
+	// `sha256=` followed by the hex of HMAC-SHA256 of `timestamp.body`, see SignWebhookPayload
+	HeaderKeyWebhookSignature = "X-Webhook-Signature"
+)
+
+const (
+	DefaultWebhookMaxAttempts  = 8
+	DefaultWebhookPollInterval = 5 * time.Second
+	DefaultWebhookBatchSize    = 100
+	// DefaultWebhookLease
+	// a claimed delivery will be attempted again after it, if the worker dies during delivering
+	DefaultWebhookLease = time.Minute
+)
+
+var (
+	NilWebhookError         = errors.New("webhook is nil")
+	NilWebhookDatabaseError = errors.New("database of webhook is nil")
+	// DuplicateWebhookModelError
+	// records of a model can only be decensored by one Crud of a Webhook, see Crud.GetWebhookCensors
+	DuplicateWebhookModelError = errors.New("model is decensored by another crud of the webhook")
+)
+
+// WebhookTarget
+// URL should be unique in a Webhook, events of all types are sent if Events is empty
+type WebhookTarget struct {
+	URL    string
+	Secret string
+	Events []EventType
+}
+
+// WebhookPayload
+// body of a delivery, Record is the decensored record of `created` and `updated` if Webhook.WithRecord is true.
+// Record is queued censored, and decensored by Crud.GetWebhookCensors only when it is sent.
+type WebhookPayload struct {
+	Model  string          `json:"model"`
+	Type   EventType       `json:"type"`
+	IDs    Keys            `json:"ids"`
+	Record json.RawMessage `json:"record,omitempty"`
+}
+
+// WebhookDelivery
+// a pending delivery in the queue, it is deleted once delivered
+type WebhookDelivery struct {
+	ID            ID              `json:"id"            gorm:"primaryKey"`
+	Model         string          `json:"model"`
+	Event         EventType       `json:"event"`
+	URL           string          `json:"url"           gorm:"index:idx_webhook_deliveries_due"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"lastError"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt" gorm:"index:idx_webhook_deliveries_due"`
+	CreatedAt     time.Time       `json:"createdAt"     gorm:"autoCreateTime"`
+}
+
+// WebhookDeadLetter
+// a delivery failed Webhook.MaxAttempts times, see SetupWebhookDeadLetterController
+type WebhookDeadLetter struct {
+	ID        ID              `json:"id"        gorm:"primaryKey"`
+	Model     string          `json:"model"`
+	Event     EventType       `json:"event"`
+	URL       string          `json:"url"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"lastError"`
+	CreatedAt time.Time       `json:"createdAt"`
+	DeadAt    time.Time       `json:"deadAt"    gorm:"autoCreateTime"`
+}
+
+// Webhook
+// sends changes of Crud.Webhook to Targets. Deliveries are queued in table of WebhookDelivery
+// in the transaction of the change, and sent by Start with retries of Backoff,
+// deliveries failed MaxAttempts times are moved to table of WebhookDeadLetter.
+type Webhook struct {
+	Targets []WebhookTarget
+	Client  *http.Client
+
+	// WithRecord
+	// WebhookPayload.Record carries the record, decensored with Crud.GetWebhookCensors
+	WithRecord bool
+
+	MaxAttempts  int
+	PollInterval time.Duration
+	BatchSize    int
+	Lease        time.Duration
+	// Backoff
+	// delay before the next attempt of a delivery failed attempts times
+	Backoff func(attempts int) time.Duration
+
+	database  *gorm.DB
+	logger    *gogger.Logger
+	notify    chan struct{}
+	decensors sync.Map // model -> func(ctx context.Context, record json.RawMessage) (json.RawMessage, error)
+}
+
+// ExponentialBackoff
+// base * 2^(attempts-1), capped by maximum
+func ExponentialBackoff(base, maximum time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		delay := base
+		for i := 1; i < attempts && delay < maximum; i++ {
+			delay *= 2
+		}
+		return min(delay, maximum)
+	}
+}
+
+// NewWebhook
+// migrates tables of WebhookDelivery and WebhookDeadLetter with database
+func NewWebhook(database *gorm.DB, logger *gogger.Logger, targets ...WebhookTarget) (*Webhook, error) {
+	if database == nil {
+		return nil, NilWebhookDatabaseError
+	}
+
+	err := database.AutoMigrate(&WebhookDelivery{}, &WebhookDeadLetter{})
+	if err != nil {
+		return nil, err
+	}
+
+	if logger == nil {
+		logger = gogger.New("webhook")
+	}
+
+	return &Webhook{
+		Targets:      targets,
+		Client:       &http.Client{Timeout: 30 * time.Second},
+		MaxAttempts:  DefaultWebhookMaxAttempts,
+		PollInterval: DefaultWebhookPollInterval,
+		BatchSize:    DefaultWebhookBatchSize,
+		Lease:        DefaultWebhookLease,
+		Backoff:      ExponentialBackoff(time.Second, time.Hour),
+		database:     database,
+		logger:       logger,
+		notify:       make(chan struct{}, 1),
+	}, nil
+}
+
+// SignWebhookPayload
+// the value of HeaderKeyWebhookSignature
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature
+// for receivers, compares signature with SignWebhookPayload in constant time
+func VerifyWebhookSignature(secret, timestamp string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, timestamp, payload)), []byte(signature))
+}
+
+func (w *Webhook) targetOf(url string) (WebhookTarget, bool) {
+	for _, target := range w.Targets {
+		if target.URL == url {
+			return target, true
+		}
+	}
+	return WebhookTarget{}, false
+}
+
+// Enqueue
+// db: use the transaction of the change, so that deliveries will be rolled back with it.
+// record is ignored unless WithRecord is true, it should be censored as it is stored,
+// so that no plaintext is persisted in the queue or dead letters, it is decensored by Crud.GetWebhookCensors when sent.
+func (w *Webhook) Enqueue(db *gorm.DB, model string, event EventType, ids []Key, record any) error {
+	payload := WebhookPayload{Model: model, Type: event, IDs: ids}
+	if w.WithRecord && record != nil {
+		content, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		payload.Record = content
+	}
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	var deliveries []WebhookDelivery
+	for _, target := range w.Targets {
+		if len(target.Events) > 0 && !slices.Contains(target.Events, event) {
+			continue
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			Model:         model,
+			Event:         event,
+			URL:           target.URL,
+			Payload:       content,
+			NextAttemptAt: now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return db.Create(&deliveries).Error
+}
+
+// Notify
+// wakes up Start to deliver without waiting for PollInterval, call it after the transaction of Enqueue is committed
+func (w *Webhook) Notify() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Start
+// delivers due deliveries every PollInterval and once notified, until ctx is done
+func (w *Webhook) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := w.DeliverDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.logger.Error().Printf("webhook: failed to deliver: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.notify:
+		}
+	}
+}
+
+// DeliverDue
+// attempts deliveries those are due once, returns the count of delivered ones
+func (w *Webhook) DeliverDue(ctx context.Context) (int, error) {
+	if len(w.Targets) == 0 {
+		return 0, nil
+	}
+
+	urls := make([]string, len(w.Targets))
+	for i, target := range w.Targets {
+		urls[i] = target.URL
+	}
+
+	db := w.database.WithContext(ctx)
+
+	var deliveries []WebhookDelivery
+	err := db.Model(&WebhookDelivery{}).
+		Where("url IN ? AND next_attempt_at <= ?", urls, time.Now()).
+		Order("`id` ASC").
+		Limit(w.BatchSize).
+		Find(&deliveries).Error
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		claimed, err := w.claim(db, delivery)
+		if err != nil {
+			return delivered, err
+		} else if !claimed {
+			continue
+		}
+
+		sendErr := w.send(ctx, delivery)
+		if ctx.Err() != nil {
+			// the lease expires, and it will be attempted again
+			return delivered, ctx.Err()
+		}
+
+		if sendErr == nil {
+			err = db.Delete(delivery).Error
+			delivered++
+		} else {
+			err = w.fail(db, delivery, sendErr)
+		}
+		if err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// claim
+// increases attempts of delivery and postpones it by Lease, false if it is claimed by another worker
+func (w *Webhook) claim(db *gorm.DB, delivery *WebhookDelivery) (bool, error) {
+	res := db.Model(&WebhookDelivery{}).
+		Where("id = ? AND attempts = ?", delivery.ID, delivery.Attempts).
+		UpdateColumns(map[string]any{
+			"attempts":        delivery.Attempts + 1,
+			"next_attempt_at": time.Now().Add(w.Lease),
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	delivery.Attempts++
+
+	return res.RowsAffected > 0, nil
+}
+
+// decensorWith
+// decensors records of model with decensor when they are sent, records of other models are sent as they are queued
+func (w *Webhook) decensorWith(model string, decensor func(ctx context.Context, record json.RawMessage) (json.RawMessage, error)) error {
+	if _, loaded := w.decensors.LoadOrStore(model, decensor); loaded {
+		return fmt.Errorf("%w: %s", DuplicateWebhookModelError, model)
+	}
+	return nil
+}
+
+// payloadOf
+// the payload of delivery to send, with its record decensored
+func (w *Webhook) payloadOf(ctx context.Context, delivery *WebhookDelivery) ([]byte, error) {
+	decensor, ok := w.decensors.Load(delivery.Model)
+	if !ok {
+		return delivery.Payload, nil
+	}
+
+	var payload WebhookPayload
+	err := json.Unmarshal(delivery.Payload, &payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload.Record) == 0 {
+		return delivery.Payload, nil
+	}
+
+	payload.Record, err = decensor.(func(context.Context, json.RawMessage) (json.RawMessage, error))(ctx, payload.Record)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(payload)
+}
+
+func (w *Webhook) send(ctx context.Context, delivery *WebhookDelivery) error {
+	target, ok := w.targetOf(delivery.URL)
+	if !ok {
+		return fmt.Errorf("target %s not found", delivery.URL)
+	}
+
+	payload, err := w.payloadOf(ctx, delivery)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderKeyWebhookDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderKeyWebhookEvent, string(delivery.Event))
+	req.Header.Set(HeaderKeyWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderKeyWebhookSignature, SignWebhookPayload(target.Secret, timestamp, payload))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// fail
+// schedules the next attempt of delivery with Backoff, or moves it to dead letters after MaxAttempts
+func (w *Webhook) fail(db *gorm.DB, delivery *WebhookDelivery, sendErr error) error {
+	if delivery.Attempts < w.MaxAttempts {
+		return db.Model(&WebhookDelivery{}).
+			Where("id = ?", delivery.ID).
+			UpdateColumns(map[string]any{
+				"last_error":      sendErr.Error(),
+				"next_attempt_at": time.Now().Add(w.Backoff(delivery.Attempts)),
+			}).Error
+	}
+
+	w.logger.Warn().Printf("webhook: delivery %d to %s is dead after %d attempts: %v", delivery.ID, delivery.URL, delivery.Attempts, sendErr)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&WebhookDeadLetter{
+			Model:     delivery.Model,
+			Event:     delivery.Event,
+			URL:       delivery.URL,
+			Payload:   delivery.Payload,
+			Attempts:  delivery.Attempts,
+			LastError: sendErr.Error(),
+			CreatedAt: delivery.CreatedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(delivery).Error
+	})
+}
+
+// Redeliver
+// moves dead letters of ids back to the queue with no attempts, returns the count of moved ones
+func (w *Webhook) Redeliver(db *gorm.DB, ids []ID) (int, error) {
+	var letters []WebhookDeadLetter
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&WebhookDeadLetter{}).Where("id IN ?", ids).Find(&letters).Error
+		if err != nil || len(letters) == 0 {
+			return err
+		}
+
+		now := time.Now()
+
+		deliveries := make([]WebhookDelivery, len(letters))
+		for i, letter := range letters {
+			deliveries[i] = WebhookDelivery{
+				Model:         letter.Model,
+				Event:         letter.Event,
+				URL:           letter.URL,
+				Payload:       letter.Payload,
+				NextAttemptAt: now,
+			}
+		}
+
+		err = tx.Create(&deliveries).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("id IN ?", ids).Delete(&WebhookDeadLetter{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(letters) > 0 {
+		w.Notify()
+	}
+
+	return len(letters), nil
+}
+
+// SetupWebhookDeadLetterController
+// dead letters of webhook are read and deleted with crud, which is read-only to dead letters,
+// `POST /redeliver/:ids` moves dead letters back to the queue, `:ids` accepts comma separated ids, such as `1,2,3`
+func SetupWebhookDeadLetterController(
+	group *gin.RouterGroup, logger *gogger.Logger,
+	webhook *Webhook,
+	crud *Crud[WebhookDeadLetter],
+) error {
+	if webhook == nil {
+		return NilWebhookError
+	}
+
+	if crud == nil {
+		crud = &Crud[WebhookDeadLetter]{}
+	}
+
+	crud.DisableSave = true
+	crud.DisableBatchSave = true
+	crud.DisablePatch = true
+	crud.EnableImport = false
+
+	if crud.Coder == nil {
+		crud.Coder = RestCoder
+	}
+	if crud.OnDelete == nil {
+		crud.OnDelete = NewHardDeleteHandler[WebhookDeadLetter](crud.Coder)
+	}
+
+	err := Setup(group, webhook.database, logger, crud)
+	if err != nil {
+		return err
+	}
+
+	crud.group.POST("/redeliver/:ids", func(context *gin.Context) {
+		ids := IDsFromCommaSeparatedString(context.Param("ids"))
+		if len(ids) == 0 {
+			crud.error(context, crud.Coder.BadRequest(), "invalid ids")
+			return
+		}
+
+		count, err := webhook.Redeliver(crud.databaseOf(context), ids)
+		if err != nil {
+			crud.logger.Error().Printf("redeliver: failed to redeliver dead letters: %v", err)
+			crud.error(context, crud.Coder.InternalServerError(), "[error] redeliver failed")
+			return
+		}
+
+		crud.ok(context, count)
+	})
+
+	if crud.OpenAPI != nil {
+		crud.OpenAPI.Add(OpenAPIRoute{
+			Method:  http.MethodPost,
+			Path:    openAPIPathOf(crud.group, "/redeliver/:ids"),
+			Tag:     openAPITagOf(crud.group),
+			Summary: "redeliver dead letters",
+			Data:    reflect.TypeFor[int](),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,369 @@
+package gocrud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	censored "github.com/allape/gocensored"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 5*time.Second)
+	for attempts, expected := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		9: 5 * time.Second,
+	} {
+		if delay := backoff(attempts); delay != expected {
+			t.Fatalf("expected %s after %d attempts, got %s", expected, attempts, delay)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	db, engine, err := basicSetup("TestWebhook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	var received []WebhookPayload
+
+	receiver := func(secret string, fail func() bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if !VerifyWebhookSignature(secret, r.Header.Get(HeaderKeyWebhookTimestamp), body, r.Header.Get(HeaderKeyWebhookSignature)) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if fail() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			var payload WebhookPayload
+			if err := json.Unmarshal(body, &payload); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if r.Header.Get(HeaderKeyWebhookEvent) != string(payload.Type) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			lock.Lock()
+			received = append(received, payload)
+			lock.Unlock()
+		}))
+	}
+
+	// the first delivery fails once
+	var flaky atomic.Int64
+	ok := receiver("secret", func() bool {
+		return flaky.Add(1) == 1
+	})
+	defer ok.Close()
+
+	var up atomic.Bool
+	down := receiver("another-secret", func() bool {
+		return !up.Load()
+	})
+	defer down.Close()
+
+	webhook, err := NewWebhook(db, nil,
+		WebhookTarget{URL: ok.URL, Secret: "secret"},
+		WebhookTarget{URL: down.URL, Secret: "another-secret", Events: []EventType{EventTypeCreated}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook.WithRecord = true
+	webhook.MaxAttempts = 3
+	webhook.Backoff = func(int) time.Duration {
+		return 0
+	}
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[User]{
+		Webhook: webhook,
+		WillSave: func(record *User, context *gin.Context, _ *gorm.DB) {
+			if record.Name == "rollback" {
+				context.AbortWithStatus(http.StatusBadRequest)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetupWebhookDeadLetterController(engine.Group("/dead-letter"), nil, webhook, &Crud[WebhookDeadLetter]{
+		EnableGetAll: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.webhook.NewAddress(0)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[User](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := crudy.Save(&User{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = crudy.Save(&User{Name: "rollback"})
+	_, err = crudy.Patch(user.ID, map[string]any{"age": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = crudy.Delete(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queued := func() int64 {
+		var count int64
+		if err := db.Model(&WebhookDelivery{}).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	if count := queued(); count != 4 {
+		t.Fatalf("expected 4 deliveries, got %d", count)
+	}
+
+	for i := 0; i < webhook.MaxAttempts && queued() > 0; i++ {
+		_, err = webhook.DeliverDue(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count := queued(); count != 0 {
+		t.Fatalf("expected empty queue, got %d", count)
+	}
+
+	lock.Lock()
+	types := map[EventType]WebhookPayload{}
+	for _, payload := range received {
+		types[payload.Type] = payload
+	}
+	lock.Unlock()
+
+	if len(types) != 3 {
+		t.Fatalf("expected created, updated and deleted, got %v", types)
+	}
+	var record User
+	if err := json.Unmarshal(types[EventTypeUpdated].Record, &record); err != nil || record.Age != 2 {
+		t.Fatalf("expected record with age 2, got %s", types[EventTypeUpdated].Record)
+	}
+	if types[EventTypeDeleted].Model != "User" || len(types[EventTypeDeleted].IDs) != 1 || types[EventTypeDeleted].IDs[0] != user.ID {
+		t.Fatalf("unexpected deleted payload %v", types[EventTypeDeleted])
+	}
+
+	// dead letters
+	letters, err := fetchJSON[[]WebhookDeadLetter](http.MethodGet, addr+"/dead-letter/all", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(letters.Data) != 1 || letters.Data[0].URL != down.URL || letters.Data[0].Attempts != webhook.MaxAttempts {
+		t.Fatalf("expected a dead letter of %s, got %v", down.URL, letters.Data)
+	}
+
+	up.Store(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	webhook.PollInterval = time.Hour
+	go webhook.Start(ctx)
+
+	redelivered, err := fetchJSON[int](http.MethodPost, fmt.Sprintf("%s/dead-letter/redeliver/%d", addr, letters.Data[0].ID), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if redelivered.Data != 1 {
+		t.Fatalf("expected 1 redelivered, got %d", redelivered.Data)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for queued() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if count := queued(); count != 0 {
+		t.Fatalf("expected redelivered, got %d in queue", count)
+	}
+
+	letters, err = fetchJSON[[]WebhookDeadLetter](http.MethodGet, addr+"/dead-letter/all", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(letters.Data) != 0 {
+		t.Fatalf("expected no dead letter, got %v", letters.Data)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(received) != 4 {
+		t.Fatalf("expected 4 received payloads, got %d", len(received))
+	}
+}
+
+func TestWebhookCensoredRecord(t *testing.T) {
+	db, engine, err := basicSetup("TestWebhookCensoredRecord.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	censor, err := censored.NewDefaultCensor(&censored.Config{
+		Password: []byte("123456789_0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	var received []WebhookPayload
+
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		received = append(received, payload)
+		lock.Unlock()
+	}))
+	defer ok.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	webhook, err := NewWebhook(db, nil, WebhookTarget{URL: ok.URL}, WebhookTarget{URL: down.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook.WithRecord = true
+	webhook.MaxAttempts = 1
+
+	err = Setup(engine.Group("/user"), db, nil, &Crud[SecretUser]{
+		Webhook: webhook,
+		GetCensors: func(_ *gin.Context, _ *gorm.DB) ([]*censored.Censor, error) {
+			return []*censored.Censor{censor}, nil
+		},
+		GetWebhookCensors: func(_ context.Context) ([]*censored.Censor, error) {
+			return []*censored.Censor{censor}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another Crud of the same model can not decensor records of the webhook
+	err = Setup(engine.Group("/another-user"), db, nil, &Crud[SecretUser]{
+		Webhook: webhook,
+		GetWebhookCensors: func(_ context.Context) ([]*censored.Censor, error) {
+			return nil, nil
+		},
+	})
+	if !errors.Is(err, DuplicateWebhookModelError) {
+		t.Fatalf("expected DuplicateWebhookModelError, got %v", err)
+	}
+
+	err = SetupWebhookDeadLetterController(engine.Group("/dead-letter"), nil, webhook, &Crud[WebhookDeadLetter]{
+		EnableGetAll: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binding = address.webhook.NewAddress(1)
+	var addr = "http://" + binding
+
+	go func() {
+		_ = engine.Run(binding)
+	}()
+
+	t.Logf("Server started on %s", binding)
+
+	wait(t)
+
+	crudy, err := NewCrudy[SecretUser](addr + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := crudy.Save(&SecretUser{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	} else if user.Name != "alice" {
+		t.Fatalf("expected decensored alice in response, got %s", user.Name)
+	}
+
+	var deliveries []WebhookDelivery
+	if err := db.Find(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	} else if len(deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(deliveries))
+	}
+	for _, delivery := range deliveries {
+		if strings.Contains(string(delivery.Payload), "alice") {
+			t.Fatalf("expected censored record in queue, got %s", delivery.Payload)
+		}
+	}
+
+	_, err = webhook.DeliverDue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	if len(received) != 1 {
+		t.Fatalf("expected 1 received payload, got %d", len(received))
+	}
+	var record SecretUser
+	if err := json.Unmarshal(received[0].Record, &record); err != nil || record.Name != "alice" {
+		t.Fatalf("expected decensored record, got %s", received[0].Record)
+	}
+	lock.Unlock()
+
+	letters, err := fetchJSON[[]WebhookDeadLetter](http.MethodGet, addr+"/dead-letter/all", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(letters.Data) != 1 {
+		t.Fatalf("expected a dead letter, got %v", letters.Data)
+	} else if strings.Contains(string(letters.Data[0].Payload), "alice") {
+		t.Fatalf("expected censored record in dead letter, got %s", letters.Data[0].Payload)
+	}
+}